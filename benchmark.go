@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -43,8 +45,16 @@ func (b *BenchmarkEngine) Run() error {
 	fmt.Println("Starting proxy benchmark...")
 
 	// Initialize metrics for each proxy
+	histogram := b.config.Statistics.Histogram
 	for _, proxy := range b.proxies {
-		b.metrics[proxy.String()] = NewMetrics(proxy.String())
+		metrics := NewMetrics(proxy.String())
+		if histogram != nil && histogram.Enabled {
+			metrics.EnableHistograms(histogram.SignificantFigures, histogram.KeepSamples)
+		}
+		// Exporters may read the metrics while the benchmark runs
+		b.mu.Lock()
+		b.metrics[proxy.String()] = metrics
+		b.mu.Unlock()
 	}
 
 	// Run warmup phase
@@ -61,10 +71,27 @@ func (b *BenchmarkEngine) Run() error {
 
 	// Run request benchmarking phase
 	fmt.Println("Running request benchmarking phase...")
+	b.startTimeSeries()
 	if err := b.runRequestBenchmarking(); err != nil {
 		return fmt.Errorf("request benchmarking phase failed: %w", err)
 	}
 
+	// Run UDP ASSOCIATE benchmarking phase if configured
+	if b.config.Benchmark.UDP != nil && b.config.Benchmark.UDP.Enabled {
+		fmt.Println("Running UDP benchmarking phase...")
+		if err := b.runUDPBenchmarking(); err != nil {
+			return fmt.Errorf("UDP benchmarking phase failed: %w", err)
+		}
+	}
+
+	// Run long-lived tunnel stability phase if configured
+	if b.config.Benchmark.Stability != nil && b.config.Benchmark.Stability.Enabled {
+		fmt.Println("Running tunnel stability phase...")
+		if err := b.runStabilityTest(); err != nil {
+			return fmt.Errorf("stability phase failed: %w", err)
+		}
+	}
+
 	// Calculate derived metrics
 	fmt.Println("Calculating derived metrics...")
 	b.calculateDerivedMetrics()
@@ -77,6 +104,24 @@ func (b *BenchmarkEngine) Run() error {
 	return nil
 }
 
+// startTimeSeries starts the time series of every proxy, if enabled, so that
+// the first bucket begins with the request phase rather than the warmup
+func (b *BenchmarkEngine) startTimeSeries() {
+	timeSeries := b.config.Statistics.TimeSeries
+	if timeSeries == nil || !timeSeries.Enabled {
+		return
+	}
+
+	significantFigures := 3
+	if histogram := b.config.Statistics.Histogram; histogram != nil && histogram.SignificantFigures > 0 {
+		significantFigures = histogram.SignificantFigures
+	}
+	start := time.Now()
+	for _, metrics := range b.metrics {
+		metrics.EnableTimeSeries(start, time.Duration(timeSeries.BucketWidthMs)*time.Millisecond, significantFigures)
+	}
+}
+
 // runWarmup executes warmup requests for each proxy
 func (b *BenchmarkEngine) runWarmup() error {
 	var wg sync.WaitGroup
@@ -97,54 +142,53 @@ func (b *BenchmarkEngine) runWarmup() error {
 func (b *BenchmarkEngine) runWarmupForProxy(proxy *Proxy) {
 	fmt.Printf("Running warmup for proxy %s...\n", proxy.Address())
 
+	switch b.targetScheme() {
+	case "tcp":
+		b.runTCPWarmupForProxy(proxy)
+		return
+	case "ws", "wss":
+		b.runWebSocketWarmupForProxy(proxy)
+		return
+	}
+
 	timeout := time.Duration(b.config.Benchmark.TimeoutMs) * time.Millisecond
 
 	for i := 0; i < b.config.Benchmark.WarmupRequests; i++ {
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
+		var body []byte
 		var err error
 		switch proxy.Protocol {
 		case "http", "https":
-			client, err := NewHTTPClient(proxy, timeout)
-			if err != nil {
-				fmt.Printf("Failed to create HTTP client for proxy %s: %v\n", proxy.Address(), err)
+			client, clientErr := NewHTTPClient(proxy, timeout)
+			if clientErr != nil {
+				fmt.Printf("Failed to create HTTP client for proxy %s: %v\n", proxy.Address(), clientErr)
 				return
 			}
-			body, err := client.MakeRequest(ctx, b.config.Benchmark.TargetURL)
-			if err == nil {
-				if b.config.Benchmark.OutputResponse {
-					fmt.Printf("Response from proxy %s (warmup):\n%s\n", proxy.Address(), string(body))
-				}
-				if b.config.Benchmark.ResponseValidation != nil && b.config.Benchmark.ResponseValidation.Enabled {
-					err = b.validateResponse(body)
-					if err == nil {
-						fmt.Printf("Response validation passed for proxy %s (warmup)\n", proxy.Address())
-					}
-				}
-			}
+			body, err = client.MakeRequest(ctx, b.config.Benchmark.TargetURL)
 		case "socks":
-			client, err := NewSOCKS5Client(proxy, timeout)
-			if err != nil {
-				fmt.Printf("Failed to create SOCKS5 client for proxy %s: %v\n", proxy.Address(), err)
+			client, clientErr := NewSOCKS5Client(proxy, timeout)
+			if clientErr != nil {
+				fmt.Printf("Failed to create SOCKS5 client for proxy %s: %v\n", proxy.Address(), clientErr)
 				return
 			}
-			body, err := client.MakeRequest(ctx, b.config.Benchmark.TargetURL)
-			if err == nil {
-				if b.config.Benchmark.OutputResponse {
-					fmt.Printf("Response from proxy %s (warmup):\n%s\n", proxy.Address(), string(body))
-				}
-				if b.config.Benchmark.ResponseValidation != nil && b.config.Benchmark.ResponseValidation.Enabled {
-					err = b.validateResponse(body)
-					if err == nil {
-						fmt.Printf("Response validation passed for proxy %s (warmup)\n", proxy.Address())
-					}
-				}
-			}
+			body, err = client.MakeRequest(ctx, b.config.Benchmark.TargetURL)
 		default:
 			fmt.Printf("Unsupported protocol for proxy %s: %s\n", proxy.Address(), proxy.Protocol)
 			return
 		}
+		if err == nil {
+			if b.config.Benchmark.OutputResponse {
+				fmt.Printf("Response from proxy %s (warmup):\n%s\n", proxy.Address(), string(body))
+			}
+			if b.config.Benchmark.ResponseValidation != nil && b.config.Benchmark.ResponseValidation.Enabled {
+				err = b.validateResponse(body)
+				if err == nil {
+					fmt.Printf("Response validation passed for proxy %s (warmup)\n", proxy.Address())
+				}
+			}
+		}
 
 		if err != nil {
 			fmt.Printf("Warmup request failed for proxy %s: %v\n", proxy.Address(), err)
@@ -184,17 +228,73 @@ func (b *BenchmarkEngine) runPingMeasurementForProxy(proxy *Proxy) {
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
+		if b.pingMode() == "handshake" {
+			// Measure TCP connect and protocol handshake on the same connection
+			connect, handshake, err := pingClient.PingHandshake(ctx, proxy, b.pingConnectTarget())
+			var connectErr *ConnectError
+			switch {
+			case errors.As(err, &connectErr):
+				fmt.Printf("Ping failed for proxy %s: %v\n", proxy.Address(), err)
+				b.metrics[proxy.String()].AddPingFailure(err)
+			case err != nil:
+				fmt.Printf("Handshake ping failed for proxy %s: %v\n", proxy.Address(), err)
+				b.metrics[proxy.String()].AddPingTime(connect)
+				b.metrics[proxy.String()].AddPingHandshakeFailure(handshakeModeFor(proxy))
+			default:
+				b.metrics[proxy.String()].AddPingTime(connect)
+				b.metrics[proxy.String()].AddPingHandshakeTime(handshakeModeFor(proxy), handshake)
+			}
+			continue
+		}
+
 		// Measure direct TCP connection time to proxy
 		duration, err := pingClient.PingProxy(ctx, proxy)
 		if err != nil {
 			fmt.Printf("Ping failed for proxy %s: %v\n", proxy.Address(), err)
-			b.metrics[proxy.String()].AddPingTime(0) // Add 0 for failed requests
+			b.metrics[proxy.String()].AddPingFailure(err)
 		} else {
 			b.metrics[proxy.String()].AddPingTime(duration)
 		}
 	}
 }
 
+// pingMode returns the configured ping mode, defaulting to a bare TCP connect
+func (b *BenchmarkEngine) pingMode() string {
+	if b.config.Benchmark.Ping == nil || b.config.Benchmark.Ping.Mode == "" {
+		return "tcp"
+	}
+	return b.config.Benchmark.Ping.Mode
+}
+
+// pingConnectTarget returns the host:port HTTP proxies are asked to CONNECT to
+// when pinging in handshake mode, defaulting to the benchmark target
+func (b *BenchmarkEngine) pingConnectTarget() string {
+	if b.config.Benchmark.Ping != nil && b.config.Benchmark.Ping.ConnectTarget != "" {
+		return b.config.Benchmark.Ping.ConnectTarget
+	}
+	target, err := url.Parse(b.config.Benchmark.TargetURL)
+	if err != nil {
+		return ""
+	}
+	if target.Port() != "" {
+		return target.Host
+	}
+	switch target.Scheme {
+	case "https", "wss":
+		return target.Hostname() + ":443"
+	default:
+		return target.Hostname() + ":80"
+	}
+}
+
+// handshakeModeFor names the handshake performed for a proxy's protocol
+func handshakeModeFor(proxy *Proxy) string {
+	if proxy.Protocol == "socks" {
+		return "socks5_auth"
+	}
+	return "http_connect"
+}
+
 // runRequestBenchmarking executes request benchmarking for each proxy
 func (b *BenchmarkEngine) runRequestBenchmarking() error {
 	var wg sync.WaitGroup
@@ -215,6 +315,15 @@ func (b *BenchmarkEngine) runRequestBenchmarking() error {
 func (b *BenchmarkEngine) runRequestBenchmarkingForProxy(proxy *Proxy) {
 	fmt.Printf("Running request benchmarking for proxy %s...\n", proxy.Address())
 
+	switch b.targetScheme() {
+	case "tcp":
+		b.runTCPBenchmarkingForProxy(proxy)
+		return
+	case "ws", "wss":
+		b.runWebSocketBenchmarkingForProxy(proxy)
+		return
+	}
+
 	timeout := time.Duration(b.config.Benchmark.TimeoutMs) * time.Millisecond
 	interval := time.Duration(b.config.Benchmark.IntervalMs) * time.Millisecond
 
@@ -227,52 +336,42 @@ func (b *BenchmarkEngine) runRequestBenchmarkingForProxy(proxy *Proxy) {
 		defer cancel()
 
 		start := time.Now()
+		var body []byte
 		var err error
 		var validationPassed bool
 		switch proxy.Protocol {
 		case "http", "https":
-			client, err := NewHTTPClient(proxy, timeout)
-			if err != nil {
-				fmt.Printf("Failed to create HTTP client for proxy %s: %v\n", proxy.Address(), err)
-				b.metrics[proxy.String()].AddRequestTime(0, false)
+			client, clientErr := NewHTTPClient(proxy, timeout)
+			if clientErr != nil {
+				fmt.Printf("Failed to create HTTP client for proxy %s: %v\n", proxy.Address(), clientErr)
+				b.metrics[proxy.String()].AddRequestFailure(time.Since(start), clientErr)
 				continue
 			}
-			body, err := client.MakeRequest(ctx, b.config.Benchmark.TargetURL)
-			if err == nil {
-				if b.config.Benchmark.OutputResponse {
-					fmt.Printf("Response from proxy %s (request %d):\n%s\n", proxy.Address(), i+1, string(body))
-				}
-				if b.config.Benchmark.ResponseValidation != nil && b.config.Benchmark.ResponseValidation.Enabled {
-					err = b.validateResponse(body)
-					if err == nil {
-						validationPassed = true
-					}
-				}
-			}
+			body, err = client.MakeRequest(ctx, b.config.Benchmark.TargetURL)
 		case "socks":
-			client, err := NewSOCKS5Client(proxy, timeout)
-			if err != nil {
-				fmt.Printf("Failed to create SOCKS5 client for proxy %s: %v\n", proxy.Address(), err)
-				b.metrics[proxy.String()].AddRequestTime(0, false)
+			client, clientErr := NewSOCKS5Client(proxy, timeout)
+			if clientErr != nil {
+				fmt.Printf("Failed to create SOCKS5 client for proxy %s: %v\n", proxy.Address(), clientErr)
+				b.metrics[proxy.String()].AddRequestFailure(time.Since(start), clientErr)
 				continue
 			}
-			body, err := client.MakeRequest(ctx, b.config.Benchmark.TargetURL)
-			if err == nil {
-				if b.config.Benchmark.OutputResponse {
-					fmt.Printf("Response from proxy %s (request %d):\n%s\n", proxy.Address(), i+1, string(body))
-				}
-				if b.config.Benchmark.ResponseValidation != nil && b.config.Benchmark.ResponseValidation.Enabled {
-					err = b.validateResponse(body)
-					if err == nil {
-						validationPassed = true
-					}
-				}
-			}
+			body, err = client.MakeRequest(ctx, b.config.Benchmark.TargetURL)
 		default:
 			fmt.Printf("Unsupported protocol for proxy %s: %s\n", proxy.Address(), proxy.Protocol)
 			b.metrics[proxy.String()].AddRequestTime(0, false)
 			continue
 		}
+		if err == nil {
+			if b.config.Benchmark.OutputResponse {
+				fmt.Printf("Response from proxy %s (request %d):\n%s\n", proxy.Address(), i+1, string(body))
+			}
+			if b.config.Benchmark.ResponseValidation != nil && b.config.Benchmark.ResponseValidation.Enabled {
+				err = b.validateAndRecord(b.metrics[proxy.String()], body)
+				if err == nil {
+					validationPassed = true
+				}
+			}
+		}
 
 		duration := time.Since(start)
 		if err != nil {
@@ -281,19 +380,43 @@ func (b *BenchmarkEngine) runRequestBenchmarkingForProxy(proxy *Proxy) {
 			} else {
 				fmt.Printf("Request failed for proxy %s: %v\n", proxy.Address(), err)
 			}
-			b.metrics[proxy.String()].AddRequestTime(duration, false)
+			b.metrics[proxy.String()].AddRequestFailure(duration, err)
 		} else {
 			if validationPassed {
 				fmt.Printf("Response validation passed for proxy %s (request %d)\n", proxy.Address(), i+1)
 			}
 			b.metrics[proxy.String()].AddRequestTime(duration, true)
+			b.metrics[proxy.String()].AddResponseBytes(len(body))
 		}
 	}
 }
 
+// targetScheme returns the lowercase scheme of the configured target URL
+func (b *BenchmarkEngine) targetScheme() string {
+	u, err := url.Parse(b.config.Benchmark.TargetURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Scheme)
+}
+
+// targetHost returns the host:port part of the configured target URL
+func (b *BenchmarkEngine) targetHost() string {
+	u, err := url.Parse(b.config.Benchmark.TargetURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
 // calculateDerivedMetrics calculates derived processing times
 func (b *BenchmarkEngine) calculateDerivedMetrics() {
 	for _, metrics := range b.metrics {
+		if !metrics.HasSamples() {
+			metrics.DeriveHistogram()
+			continue
+		}
+
 		requestTimes := metrics.GetRequestTimes()
 		pingTimes := metrics.GetPingTimes()
 
@@ -322,22 +445,66 @@ func (b *BenchmarkEngine) validateResponse(body []byte) error {
 		return nil
 	}
 
+	results, err := b.checkResponse(body)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result != nil {
+			return result
+		}
+	}
+
+	return nil
+}
+
+// checkResponse runs every validation check against body and returns one result
+// per check, nil for the checks that passed. It fails as a whole only if the
+// body is not a JSON object.
+func (b *BenchmarkEngine) checkResponse(body []byte) ([]error, error) {
 	var data map[string]interface{}
 	if err := json.Unmarshal(body, &data); err != nil {
-		return fmt.Errorf("failed to parse JSON response: %w", err)
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
 	}
 
-	for _, check := range b.config.Benchmark.ResponseValidation.Checks {
+	checks := b.config.Benchmark.ResponseValidation.Checks
+	results := make([]error, len(checks))
+	for i, check := range checks {
 		value, err := getNestedValue(data, check.Path)
 		if err != nil {
-			return fmt.Errorf("validation failed for path '%s': %w", check.Path, err)
+			results[i] = fmt.Errorf("validation failed for path '%s': %w", check.Path, err)
+			continue
 		}
 
 		if err := validateType(value, check.Type, check.Value); err != nil {
-			return fmt.Errorf("validation failed for path '%s': %w", check.Path, err)
+			results[i] = fmt.Errorf("validation failed for path '%s': %w", check.Path, err)
+		}
+	}
+
+	return results, nil
+}
+
+// validateAndRecord validates a response like validateResponse and records the
+// outcome of every check in the proxy's metrics
+func (b *BenchmarkEngine) validateAndRecord(metrics *Metrics, body []byte) error {
+	checks := b.config.Benchmark.ResponseValidation.Checks
+	results, err := b.checkResponse(body)
+	if err != nil {
+		results = make([]error, len(checks))
+		for i := range results {
+			results[i] = err
 		}
 	}
+	metrics.AddValidationResults(checks, results)
 
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result != nil {
+			return result
+		}
+	}
 	return nil
 }
 
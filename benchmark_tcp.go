@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// runTCPWarmupForProxy executes warmup tunnel exchanges for a single proxy
+func (b *BenchmarkEngine) runTCPWarmupForProxy(proxy *Proxy) {
+	timeout := time.Duration(b.config.Benchmark.TimeoutMs) * time.Millisecond
+
+	client, err := NewTCPClient(proxy, timeout, b.config.Benchmark.TCP)
+	if err != nil {
+		fmt.Printf("Failed to create TCP client for proxy %s: %v\n", proxy.Address(), err)
+		return
+	}
+
+	for i := 0; i < b.config.Benchmark.WarmupRequests; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		_, err := client.Exchange(ctx, b.targetHost())
+		cancel()
+
+		if err != nil {
+			fmt.Printf("Warmup tunnel failed for proxy %s: %v\n", proxy.Address(), err)
+		}
+	}
+}
+
+// runTCPBenchmarkingForProxy executes raw TCP tunnel benchmarking for a single proxy.
+// The full exchange time is recorded as the request time so that derived metrics
+// and statistics work the same way as for HTTP targets.
+func (b *BenchmarkEngine) runTCPBenchmarkingForProxy(proxy *Proxy) {
+	timeout := time.Duration(b.config.Benchmark.TimeoutMs) * time.Millisecond
+	interval := time.Duration(b.config.Benchmark.IntervalMs) * time.Millisecond
+	metrics := b.metrics[proxy.String()]
+
+	client, err := NewTCPClient(proxy, timeout, b.config.Benchmark.TCP)
+	if err != nil {
+		fmt.Printf("Failed to create TCP client for proxy %s: %v\n", proxy.Address(), err)
+		return
+	}
+
+	for i := 0; i < b.config.Benchmark.Requests; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		result, err := client.Exchange(ctx, b.targetHost())
+		duration := time.Since(start)
+		cancel()
+
+		if err != nil {
+			fmt.Printf("Tunnel exchange failed for proxy %s: %v\n", proxy.Address(), err)
+			metrics.AddRequestFailure(duration, err)
+			continue
+		}
+
+		if b.config.Benchmark.OutputResponse && len(result.Response) > 0 {
+			fmt.Printf("Response from proxy %s (request %d):\n%s\n", proxy.Address(), i+1, string(result.Response))
+		}
+		metrics.AddTunnelTime(result.Establish, result.Echo, result.Exchanged)
+		metrics.AddRequestTime(duration, true)
+		metrics.AddResponseBytes(len(result.Response))
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// runUDPBenchmarking executes UDP ASSOCIATE benchmarking for each SOCKS5 proxy
+func (b *BenchmarkEngine) runUDPBenchmarking() error {
+	var wg sync.WaitGroup
+
+	for _, proxy := range b.proxies {
+		if proxy.Protocol != "socks" {
+			continue
+		}
+		wg.Add(1)
+		go func(p *Proxy) {
+			defer wg.Done()
+			b.runUDPBenchmarkingForProxy(p)
+		}(proxy)
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// runUDPBenchmarkingForProxy negotiates UDP associations for a single proxy and
+// sends datagrams to the configured echo target through each of them
+func (b *BenchmarkEngine) runUDPBenchmarkingForProxy(proxy *Proxy) {
+	fmt.Printf("Running UDP benchmarking for proxy %s...\n", proxy.Address())
+
+	udpConfig := b.config.Benchmark.UDP
+	timeout := time.Duration(b.config.Benchmark.TimeoutMs) * time.Millisecond
+	interval := time.Duration(udpConfig.IntervalMs) * time.Millisecond
+	datagramTimeout := time.Duration(udpConfig.DatagramTimeoutMs) * time.Millisecond
+	metrics := b.metrics[proxy.String()]
+
+	client, err := NewUDPClient(proxy, timeout)
+	if err != nil {
+		fmt.Printf("Failed to create UDP client for proxy %s: %v\n", proxy.Address(), err)
+		return
+	}
+
+	for i := 0; i < udpConfig.Associations; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		association, err := client.Associate(ctx, udpConfig.Target)
+		setup := time.Since(start)
+		cancel()
+
+		if err != nil {
+			fmt.Printf("UDP ASSOCIATE failed for proxy %s: %v\n", proxy.Address(), err)
+			metrics.AddUDPAssociation(setup, false)
+			continue
+		}
+		metrics.AddUDPAssociation(setup, true)
+
+		for j := 0; j < udpConfig.Datagrams; j++ {
+			if j > 0 {
+				time.Sleep(interval)
+			}
+
+			rtt, err := association.RoundTrip([]byte(udpConfig.Payload), datagramTimeout)
+			if err != nil {
+				fmt.Printf("UDP datagram lost for proxy %s: %v\n", proxy.Address(), err)
+			}
+			metrics.AddUDPDatagram(rtt, err == nil)
+		}
+
+		association.Close()
+	}
+}
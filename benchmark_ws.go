@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// runWebSocketWarmupForProxy executes warmup WebSocket handshakes for a single proxy
+func (b *BenchmarkEngine) runWebSocketWarmupForProxy(proxy *Proxy) {
+	timeout := time.Duration(b.config.Benchmark.TimeoutMs) * time.Millisecond
+	client := NewWebSocketClient(proxy, timeout, b.config.Benchmark.WebSocket.Origin)
+
+	for i := 0; i < b.config.Benchmark.WarmupRequests; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		ws, _, err := client.Connect(ctx, b.config.Benchmark.TargetURL)
+		cancel()
+
+		if err != nil {
+			fmt.Printf("Warmup WebSocket handshake failed for proxy %s: %v\n", proxy.Address(), err)
+			continue
+		}
+		ws.Close()
+	}
+}
+
+// runWebSocketBenchmarkingForProxy executes WebSocket benchmarking for a single proxy.
+// Each request opens a connection, exchanges the configured number of messages and
+// records how long the connection survived. The handshake time is recorded as the
+// request time so that derived metrics work the same way as for HTTP targets.
+func (b *BenchmarkEngine) runWebSocketBenchmarkingForProxy(proxy *Proxy) {
+	wsConfig := b.config.Benchmark.WebSocket
+	timeout := time.Duration(b.config.Benchmark.TimeoutMs) * time.Millisecond
+	interval := time.Duration(b.config.Benchmark.IntervalMs) * time.Millisecond
+	messageInterval := time.Duration(wsConfig.MessageIntervalMs) * time.Millisecond
+	metrics := b.metrics[proxy.String()]
+	client := NewWebSocketClient(proxy, timeout, wsConfig.Origin)
+
+	for i := 0; i < b.config.Benchmark.Requests; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		start := time.Now()
+		ws, handshake, err := client.Connect(ctx, b.config.Benchmark.TargetURL)
+		cancel()
+
+		if err != nil {
+			fmt.Printf("WebSocket handshake failed for proxy %s: %v\n", proxy.Address(), err)
+			metrics.AddWebSocketHandshake(0, false)
+			metrics.AddRequestFailure(time.Since(start), err)
+			continue
+		}
+		metrics.AddWebSocketHandshake(handshake, true)
+		metrics.AddRequestTime(handshake, true)
+
+		connected := time.Now()
+		dropped := false
+		for j := 0; j < wsConfig.Messages; j++ {
+			if j > 0 {
+				time.Sleep(messageInterval)
+			}
+
+			rtt, err := client.RoundTrip(ws, wsConfig.Payload)
+			if err != nil {
+				fmt.Printf("WebSocket connection dropped for proxy %s: %v\n", proxy.Address(), err)
+				dropped = true
+				break
+			}
+			metrics.AddWebSocketRTT(rtt)
+		}
+
+		metrics.AddWebSocketLifetime(time.Since(connected), dropped)
+		ws.Close()
+	}
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// RunComparison holds the per-proxy differences between a baseline and a current
+// run. Latencies are in Unit, the current run's display unit.
+type RunComparison struct {
+	Baseline    string        `json:"baseline"`
+	Current     string        `json:"current"`
+	Unit        string        `json:"unit"`
+	Passed      bool          `json:"passed"`
+	Proxies     []*ProxyDelta `json:"proxies"`
+	Added       []string      `json:"added,omitempty"`
+	Removed     []string      `json:"removed,omitempty"`
+	Regressions []string      `json:"regressions,omitempty"`
+}
+
+// ProxyDelta holds the compared metrics of a proxy present in both runs
+type ProxyDelta struct {
+	Proxy     string         `json:"proxy"`
+	Regressed bool           `json:"regressed"`
+	Metrics   []*MetricDelta `json:"metrics"`
+}
+
+// MetricDelta is the change of one metric. The success rate is in percent and
+// its delta in percentage points; DeltaPercent is the relative change.
+type MetricDelta struct {
+	Metric       string  `json:"metric"`
+	Baseline     float64 `json:"baseline"`
+	Current      float64 `json:"current"`
+	Delta        float64 `json:"delta"`
+	DeltaPercent float64 `json:"delta_percent"`
+	Regression   bool    `json:"regression"`
+}
+
+// Default regression thresholds of unset CompareConfig fields
+const (
+	defaultMaxSuccessRateDrop        = 1.0
+	defaultMaxLatencyIncreasePercent = 10.0
+)
+
+// setCompareDefaults fills in the regression thresholds that were not configured
+func setCompareDefaults(compare *CompareConfig) {
+	if compare.MaxSuccessRateDrop == nil {
+		value := defaultMaxSuccessRateDrop
+		compare.MaxSuccessRateDrop = &value
+	}
+	if compare.MaxLatencyIncreasePercent == nil {
+		value := defaultMaxLatencyIncreasePercent
+		compare.MaxLatencyIncreasePercent = &value
+	}
+}
+
+// validateCompareConfig rejects negative thresholds and out-of-range percentiles.
+// It expects setCompareDefaults to have run.
+func validateCompareConfig(compare *CompareConfig) error {
+	if *compare.MaxSuccessRateDrop < 0 || *compare.MaxLatencyIncreasePercent < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	for _, p := range compare.Percentiles {
+		if p <= 0 || p >= 100 {
+			return fmt.Errorf("percentile %v must be between 0 and 100", p)
+		}
+	}
+	return nil
+}
+
+// loadResult reads a result.json file. Results written before the unit was
+// recorded have their latencies in milliseconds.
+func loadResult(path string) (*BenchmarkResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var result BenchmarkResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if result.SchemaVersion > resultSchemaVersion {
+		return nil, fmt.Errorf("%s has schema version %d, newer than the supported %d", path, result.SchemaVersion, resultSchemaVersion)
+	}
+	if result.Unit == "" {
+		result.Unit = "ms"
+	}
+	return &result, nil
+}
+
+// compareResults matches the proxies of two runs by ID and flags a regression
+// when the success rate drops by more than MaxSuccessRateDrop percentage points
+// or a latency percentile grows by more than MaxLatencyIncreasePercent
+func compareResults(baseline, current *BenchmarkResult, compare *CompareConfig) *RunComparison {
+	comparison := &RunComparison{
+		Baseline: runLabel(baseline),
+		Current:  runLabel(current),
+		Unit:     current.Unit,
+		Passed:   true,
+	}
+
+	baselineProxies := make(map[string]*ProxyMetrics, len(baseline.Proxies))
+	for _, proxy := range baseline.Proxies {
+		baselineProxies[proxyID(proxy.ProxyString)] = proxy
+	}
+
+	seen := make(map[string]bool)
+	for _, proxy := range sortedProxies(current) {
+		id := proxyID(proxy.ProxyString)
+		seen[id] = true
+		before, ok := baselineProxies[id]
+		if !ok {
+			comparison.Added = append(comparison.Added, id)
+			continue
+		}
+
+		delta := compareProxy(id, before, baseline.Unit, proxy, current.Unit, compare)
+		comparison.Proxies = append(comparison.Proxies, delta)
+		for _, metric := range delta.Metrics {
+			if metric.Regression {
+				comparison.Passed = false
+				comparison.Regressions = append(comparison.Regressions, regressionMessage(id, metric, current.Unit))
+			}
+		}
+	}
+
+	for id := range baselineProxies {
+		if !seen[id] {
+			comparison.Removed = append(comparison.Removed, id)
+		}
+	}
+	sort.Strings(comparison.Removed)
+	return comparison
+}
+
+// compareProxy compares the success rate and request latency percentiles of a
+// proxy, converting baseline latencies to the current run's unit
+func compareProxy(id string, before *ProxyMetrics, beforeUnit string, after *ProxyMetrics, afterUnit string, compare *CompareConfig) *ProxyDelta {
+	delta := &ProxyDelta{Proxy: id}
+
+	if before.RequestMetrics.Total > 0 && after.RequestMetrics.Total > 0 {
+		metric := newMetricDelta("success_rate", successRatePercent(before), successRatePercent(after))
+		metric.Regression = -metric.Delta > *compare.MaxSuccessRateDrop
+		delta.Metrics = append(delta.Metrics, metric)
+	}
+
+	beforeStat, afterStat := before.RequestMetrics.Statistics, after.RequestMetrics.Statistics
+	if beforeStat != nil && afterStat != nil {
+		for _, key := range comparedPercentiles(beforeStat, afterStat, compare.Percentiles) {
+			baseline := fromMicros(toMicros(beforeStat.Percentiles[key], beforeUnit), afterUnit)
+			metric := newMetricDelta("p"+strings.TrimSuffix(key, ".0"), baseline, afterStat.Percentiles[key])
+			metric.Regression = baseline > 0 && metric.DeltaPercent > *compare.MaxLatencyIncreasePercent
+			delta.Metrics = append(delta.Metrics, metric)
+		}
+	}
+
+	for _, metric := range delta.Metrics {
+		if metric.Regression {
+			delta.Regressed = true
+		}
+	}
+	return delta
+}
+
+// comparedPercentiles returns the configured percentiles, or every percentile
+// reported in both runs, in ascending order
+func comparedPercentiles(before, after *Statistics, percentiles []float64) []string {
+	var keys []string
+	if len(percentiles) > 0 {
+		for _, p := range percentiles {
+			key := fmt.Sprintf("%.1f", p)
+			if _, ok := before.Percentiles[key]; !ok {
+				continue
+			}
+			if _, ok := after.Percentiles[key]; !ok {
+				continue
+			}
+			keys = append(keys, key)
+		}
+	} else {
+		for key := range after.Percentiles {
+			if _, ok := before.Percentiles[key]; ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		pi, _ := strconv.ParseFloat(keys[i], 64)
+		pj, _ := strconv.ParseFloat(keys[j], 64)
+		return pi < pj
+	})
+	return keys
+}
+
+// newMetricDelta computes the absolute and relative change of a metric
+func newMetricDelta(metric string, baseline, current float64) *MetricDelta {
+	delta := &MetricDelta{
+		Metric:   metric,
+		Baseline: baseline,
+		Current:  current,
+		Delta:    current - baseline,
+	}
+	if baseline != 0 {
+		delta.DeltaPercent = delta.Delta / baseline * 100
+	}
+	return delta
+}
+
+// successRatePercent returns a proxy's request success rate in percent
+func successRatePercent(proxy *ProxyMetrics) float64 {
+	return float64(proxy.RequestMetrics.Successful) / float64(proxy.RequestMetrics.Total) * 100
+}
+
+// regressionMessage describes a regressed metric
+func regressionMessage(id string, metric *MetricDelta, unit string) string {
+	if metric.Metric == "success_rate" {
+		return fmt.Sprintf("%s: success rate dropped from %.2f%% to %.2f%%", id, metric.Baseline, metric.Current)
+	}
+	return fmt.Sprintf("%s: %s grew from %.2f%s to %.2f%s (%+.1f%%)",
+		id, metric.Metric, metric.Baseline, unit, metric.Current, unit, metric.DeltaPercent)
+}
+
+// runLabel identifies a run by its ID, falling back to its timestamp
+func runLabel(result *BenchmarkResult) string {
+	if result.RunID != "" {
+		return result.RunID
+	}
+	return result.Timestamp.Format("2006-01-02 15:04:05")
+}
+
+// WriteComparison prints a comparison as a table followed by its regressions
+func WriteComparison(w io.Writer, comparison *RunComparison) error {
+	fmt.Fprintf(w, "Comparing %s (baseline) with %s (current), latencies in %s\n\n",
+		comparison.Baseline, comparison.Current, comparison.Unit)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PROXY\tMETRIC\tBASELINE\tCURRENT\tDELTA\tDELTA %\t")
+	for _, proxy := range comparison.Proxies {
+		for _, metric := range proxy.Metrics {
+			marker := ""
+			if metric.Regression {
+				marker = "REGRESSION"
+			}
+			fmt.Fprintf(table, "%s\t%s\t%.2f\t%.2f\t%+.2f\t%+.1f%%\t%s\n",
+				proxy.Proxy, metric.Metric, metric.Baseline, metric.Current, metric.Delta, metric.DeltaPercent, marker)
+		}
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	for _, id := range comparison.Added {
+		fmt.Fprintf(w, "\nOnly in current run: %s", id)
+	}
+	for _, id := range comparison.Removed {
+		fmt.Fprintf(w, "\nOnly in baseline run: %s", id)
+	}
+	if len(comparison.Added)+len(comparison.Removed) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	if comparison.Passed {
+		fmt.Fprintln(w, "\nNo regressions")
+		return nil
+	}
+	fmt.Fprintf(w, "\n%d regression(s):\n", len(comparison.Regressions))
+	for _, regression := range comparison.Regressions {
+		fmt.Fprintf(w, "  - %s\n", regression)
+	}
+	return nil
+}
+
+// SaveComparison saves a comparison as JSON
+func SaveComparison(comparison *RunComparison, filepath string) error {
+	data, err := json.MarshalIndent(comparison, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal comparison: %w", err)
+	}
+	return os.WriteFile(filepath, data, 0644)
+}
+
+// runCompare implements the compare command and returns the process exit code:
+// 0 without regressions, 2 with regressions and 1 on errors
+func runCompare(args []string) int {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	configPath := flags.String("config", "", "Configuration file to read the compare thresholds from")
+	maxSuccessDrop := flags.Float64("max-success-drop", defaultMaxSuccessRateDrop, "Largest allowed success rate drop in percentage points")
+	maxLatencyIncrease := flags.Float64("max-latency-increase", defaultMaxLatencyIncreasePercent, "Largest allowed latency percentile increase in percent")
+	percentiles := flags.String("percentiles", "", "Comma-separated percentiles to compare (default: all reported in both runs)")
+	output := flags.String("output", "", "Also save the comparison as JSON to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: proxy-benchmark compare [flags] <baseline result.json> <current result.json>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 1
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 1
+	}
+
+	compare := &CompareConfig{}
+	if *configPath != "" {
+		config, err := LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+			return 1
+		}
+		if config.Compare != nil {
+			compare = config.Compare
+		}
+	}
+	// Flags given on the command line override the configuration file
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "max-success-drop":
+			compare.MaxSuccessRateDrop = maxSuccessDrop
+		case "max-latency-increase":
+			compare.MaxLatencyIncreasePercent = maxLatencyIncrease
+		}
+	})
+	if *percentiles != "" {
+		compare.Percentiles = nil
+		for _, value := range strings.Split(*percentiles, ",") {
+			p, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid percentile %q\n", value)
+				return 1
+			}
+			compare.Percentiles = append(compare.Percentiles, p)
+		}
+	}
+	setCompareDefaults(compare)
+	if err := validateCompareConfig(compare); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid compare configuration: %v\n", err)
+		return 1
+	}
+
+	baseline, err := loadResult(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load baseline: %v\n", err)
+		return 1
+	}
+	current, err := loadResult(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load current run: %v\n", err)
+		return 1
+	}
+
+	comparison := compareResults(baseline, current, compare)
+	WriteComparison(os.Stdout, comparison)
+	if *output != "" {
+		if err := SaveComparison(comparison, *output); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save comparison: %v\n", err)
+			return 1
+		}
+	}
+
+	if !comparison.Passed {
+		return 2
+	}
+	return 0
+}
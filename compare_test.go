@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func comparedResult(unit string, proxies map[string][2]float64) *BenchmarkResult {
+	result := &BenchmarkResult{RunID: "run-" + unit, Unit: unit}
+	for proxyString, values := range proxies {
+		proxy := &ProxyMetrics{ProxyString: proxyString}
+		proxy.RequestMetrics.Total = 100
+		proxy.RequestMetrics.Successful = int(values[0])
+		proxy.RequestMetrics.Statistics = &Statistics{Percentiles: map[string]float64{"50.0": values[1] / 2, "95.0": values[1]}}
+		result.Proxies = append(result.Proxies, proxy)
+	}
+	return result
+}
+
+func defaultCompareConfig() *CompareConfig {
+	compare := &CompareConfig{}
+	setCompareDefaults(compare)
+	return compare
+}
+
+func TestCompareResultsFlagsRegressions(t *testing.T) {
+	baseline := comparedResult("ms", map[string][2]float64{
+		"http:a.example:8080:user:pass:enabled": {99, 200},
+		"http:b.example:8080:::enabled":         {99, 200},
+		"http:gone.example:8080:::enabled":      {99, 200},
+	})
+	// The current run reports in seconds and a proxy's credentials changed
+	current := comparedResult("s", map[string][2]float64{
+		"http:a.example:8080:other:secret:enabled": {97, 0.21},
+		"http:b.example:8080:::enabled":            {99, 0.3},
+		"http:new.example:8080:::enabled":          {99, 0.2},
+	})
+
+	comparison := compareResults(baseline, current, defaultCompareConfig())
+	if comparison.Passed {
+		t.Fatal("expected regressions")
+	}
+	if len(comparison.Proxies) != 2 {
+		t.Fatalf("expected 2 matched proxies, got %d", len(comparison.Proxies))
+	}
+	if got := strings.Join(comparison.Added, ","); got != "http://new.example:8080" {
+		t.Errorf("unexpected added proxies %q", got)
+	}
+	if got := strings.Join(comparison.Removed, ","); got != "http://gone.example:8080" {
+		t.Errorf("unexpected removed proxies %q", got)
+	}
+
+	a := comparison.Proxies[0]
+	if a.Proxy != "http://a.example:8080" || !a.Regressed {
+		t.Fatalf("expected a.example to regress, got %+v", a)
+	}
+	if a.Metrics[0].Metric != "success_rate" || a.Metrics[0].Delta != -2 || !a.Metrics[0].Regression {
+		t.Errorf("unexpected success rate delta %+v", a.Metrics[0])
+	}
+	p95 := a.Metrics[2]
+	if p95.Metric != "p95" || p95.Baseline != 0.2 || p95.Regression {
+		t.Errorf("expected a 5%% p95 increase within the threshold, got %+v", p95)
+	}
+
+	b := comparison.Proxies[1]
+	if b.Metrics[0].Regression || !b.Metrics[2].Regression {
+		t.Errorf("expected only b.example's p95 to regress, got %+v %+v", b.Metrics[0], b.Metrics[2])
+	}
+	if len(comparison.Regressions) != 3 {
+		t.Errorf("expected 3 regressions, got %v", comparison.Regressions)
+	}
+}
+
+func TestCompareResultsSelectedPercentiles(t *testing.T) {
+	baseline := comparedResult("ms", map[string][2]float64{"http:a.example:8080:::enabled": {100, 200}})
+	current := comparedResult("ms", map[string][2]float64{"http:a.example:8080:::enabled": {100, 400}})
+
+	compare := &CompareConfig{Percentiles: []float64{99, 50}}
+	setCompareDefaults(compare)
+	comparison := compareResults(baseline, current, compare)
+	metrics := comparison.Proxies[0].Metrics
+	if len(metrics) != 2 || metrics[1].Metric != "p50" {
+		t.Fatalf("expected success rate and p50 only, got %+v", metrics)
+	}
+}
+
+func TestRunCompareExitCode(t *testing.T) {
+	dir := t.TempDir()
+	reporter := NewReporter(&Config{})
+	baselinePath := filepath.Join(dir, "baseline.json")
+	currentPath := filepath.Join(dir, "current.json")
+	if err := reporter.SaveReport(comparedResult("ms", map[string][2]float64{"http:a.example:8080:::enabled": {100, 200}}), baselinePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := reporter.SaveReport(comparedResult("ms", map[string][2]float64{"http:a.example:8080:::enabled": {100, 240}}), currentPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runCompare([]string{baselinePath, currentPath}); code != 2 {
+		t.Errorf("expected exit code 2 for a 20%% regression, got %d", code)
+	}
+	if code := runCompare([]string{"-max-latency-increase", "25", baselinePath, currentPath}); code != 0 {
+		t.Errorf("expected exit code 0 within a 25%% threshold, got %d", code)
+	}
+	if code := runCompare([]string{baselinePath, filepath.Join(dir, "missing.json")}); code != 1 {
+		t.Errorf("expected exit code 1 for a missing file, got %d", code)
+	}
+	if code := runCompare([]string{"-max-latency-increase", "lots", baselinePath, currentPath}); code != 1 {
+		t.Errorf("expected exit code 1 for an invalid flag value, got %d", code)
+	}
+}
+
+func TestCompareZeroTolerance(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"compare": {"max_success_rate_drop": 0, "max_latency_increase_percent": 0}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reporter := NewReporter(&Config{})
+	baselinePath := filepath.Join(dir, "baseline.json")
+	currentPath := filepath.Join(dir, "current.json")
+	if err := reporter.SaveReport(comparedResult("ms", map[string][2]float64{"http:a.example:8080:::enabled": {100, 200}}), baselinePath); err != nil {
+		t.Fatal(err)
+	}
+	if err := reporter.SaveReport(comparedResult("ms", map[string][2]float64{"http:a.example:8080:::enabled": {100, 202}}), currentPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runCompare([]string{"-config", configPath, baselinePath, currentPath}); code != 2 {
+		t.Errorf("expected a 1%% increase to fail a zero tolerance from the config, got exit code %d", code)
+	}
+	if code := runCompare([]string{"-max-latency-increase", "0", baselinePath, currentPath}); code != 2 {
+		t.Errorf("expected a 1%% increase to fail a zero tolerance flag, got exit code %d", code)
+	}
+}
+
+func TestLoadResultWithoutUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "result.json")
+	if err := os.WriteFile(path, []byte(`{"proxies": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := loadResult(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Unit != "ms" {
+		t.Errorf("expected results without a unit to be read as ms, got %q", result.Unit)
+	}
+}
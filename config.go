@@ -10,6 +10,135 @@ type Config struct {
 	Proxies    []string         `json:"proxies"`
 	Benchmark  BenchmarkConfig  `json:"benchmark"`
 	Statistics StatisticsConfig `json:"statistics"`
+	Report     ReportConfig     `json:"report"`
+	Output     OutputConfig     `json:"output"`
+	Exporters  ExportersConfig  `json:"exporters"`
+	Thresholds []ThresholdRule  `json:"thresholds,omitempty"`
+	Compare    *CompareConfig   `json:"compare,omitempty"`
+	History    *HistoryConfig   `json:"history,omitempty"`
+}
+
+// HistoryConfig holds the history store every run is appended to
+type HistoryConfig struct {
+	Path string `json:"path,omitempty"`
+}
+
+// CompareConfig holds the regression thresholds used when comparing runs.
+// MaxSuccessRateDrop is in percentage points and MaxLatencyIncreasePercent is
+// the allowed relative growth of each compared request latency percentile.
+// Both are pointers so that an explicit 0 (no tolerance) differs from unset.
+// If Baseline names a result.json file, every run is compared against it.
+type CompareConfig struct {
+	Baseline                  string    `json:"baseline,omitempty"`
+	MaxSuccessRateDrop        *float64  `json:"max_success_rate_drop,omitempty"`
+	MaxLatencyIncreasePercent *float64  `json:"max_latency_increase_percent,omitempty"`
+	Percentiles               []float64 `json:"percentiles,omitempty"`
+}
+
+// ThresholdRule is a pass/fail gate on the benchmark results. Proxy restricts
+// the rule to one proxy (ID or host:port); MinProxies turns it into "at least
+// N proxies must meet the rule" instead of "every proxy must".
+type ThresholdRule struct {
+	Name           string  `json:"name,omitempty"`
+	Proxy          string  `json:"proxy,omitempty"`
+	MinProxies     int     `json:"min_proxies,omitempty"`
+	MinSuccessRate float64 `json:"min_success_rate,omitempty"`
+	Percentile     float64 `json:"percentile,omitempty"`
+	MaxLatencyMs   float64 `json:"max_latency_ms,omitempty"`
+}
+
+// ExportersConfig holds exporters that publish results to monitoring systems
+type ExportersConfig struct {
+	Prometheus *PrometheusConfig `json:"prometheus,omitempty"`
+	Influx     *InfluxConfig     `json:"influx,omitempty"`
+}
+
+// PrometheusConfig holds the Prometheus exporter configuration. Listen serves
+// live metrics on /metrics while the benchmark runs; Textfile writes the final
+// metrics for the node_exporter textfile collector.
+type PrometheusConfig struct {
+	Listen    string `json:"listen,omitempty"`
+	Textfile  string `json:"textfile,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// InfluxConfig holds the InfluxDB line protocol exporter configuration. Points
+// are written to File (which may use the output placeholders), POSTed to URL,
+// or both. Groups maps proxy IDs or host:port to the value of the group tag.
+type InfluxConfig struct {
+	File        string            `json:"file,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	Token       string            `json:"token,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Mode        string            `json:"mode,omitempty"`
+	Measurement string            `json:"measurement,omitempty"`
+	Groups      map[string]string `json:"groups,omitempty"`
+}
+
+// OutputConfig holds where reports are written. Dir and NameTemplate may use
+// the placeholders {run_id}, {timestamp} and {date}; NameTemplate must also
+// contain {name}, the output's default base name such as "result".
+type OutputConfig struct {
+	Dir          string `json:"dir,omitempty"`
+	NameTemplate string `json:"name_template,omitempty"`
+	// RunID identifies the run in file names and reports; generated if empty
+	RunID string `json:"run_id,omitempty"`
+}
+
+// ReportConfig holds report presentation configuration
+type ReportConfig struct {
+	// Outputs lists the reports to write: "json", "short", "junit", "html",
+	// "csv", "samples_csv" and "markdown"
+	Outputs []string `json:"outputs,omitempty"`
+	// Unit is the display unit for reported statistics: "ns", "us", "ms" or "s"
+	Unit         string              `json:"unit,omitempty"`
+	Significance *SignificanceConfig `json:"significance,omitempty"`
+	Scoring      *ScoringConfig      `json:"scoring,omitempty"`
+	SLOs         []SLOConfig         `json:"slos,omitempty"`
+	Apdex        *ApdexConfig        `json:"apdex,omitempty"`
+}
+
+// SLOConfig defines a service level objective evaluated per proxy, e.g. "95% of
+// requests under 800ms with 99% success". Objectives left at zero are skipped.
+type SLOConfig struct {
+	Name       string  `json:"name"`
+	Percentile float64 `json:"percentile,omitempty"`
+	LatencyMs  float64 `json:"latency_ms,omitempty"`
+	// MinSuccessRate is the minimum share of successful requests in percent
+	MinSuccessRate float64 `json:"min_success_rate,omitempty"`
+	// MaxErrorRates limits the share of requests failing with an error class,
+	// in percent; the class "any" covers all failures
+	MaxErrorRates map[string]float64 `json:"max_error_rates,omitempty"`
+}
+
+// ApdexConfig holds the Apdex response time thresholds
+type ApdexConfig struct {
+	SatisfiedMs  float64 `json:"satisfied_ms"`
+	ToleratingMs float64 `json:"tolerating_ms,omitempty"`
+}
+
+// ScoringConfig holds the composite proxy scoring model
+type ScoringConfig struct {
+	Weights *ScoringWeights `json:"weights,omitempty"`
+	// Costs maps a proxy ID (protocol://host:port) or host:port to its price.
+	// Proxies without a configured cost are treated as free.
+	Costs map[string]float64 `json:"costs,omitempty"`
+}
+
+// ScoringWeights holds the relative weight of each scoring component
+type ScoringWeights struct {
+	SuccessRate float64 `json:"success_rate"`
+	P50         float64 `json:"p50"`
+	P95         float64 `json:"p95"`
+	Jitter      float64 `json:"jitter"`
+	Throughput  float64 `json:"throughput"`
+	Cost        float64 `json:"cost"`
+}
+
+// SignificanceConfig holds pairwise proxy comparison configuration
+type SignificanceConfig struct {
+	Enabled bool    `json:"enabled"`
+	Alpha   float64 `json:"alpha"`
 }
 
 // BenchmarkConfig holds benchmark-specific configuration
@@ -22,6 +151,56 @@ type BenchmarkConfig struct {
 	TimeoutMs          int                 `json:"timeout_ms"`
 	ResponseValidation *ResponseValidation `json:"response_validation,omitempty"`
 	OutputResponse     bool                `json:"output_response,omitempty"`
+	TCP                *TCPTargetConfig    `json:"tcp,omitempty"`
+	UDP                *UDPBenchmarkConfig `json:"udp,omitempty"`
+	WebSocket          *WebSocketConfig    `json:"websocket,omitempty"`
+	Stability          *StabilityConfig    `json:"stability,omitempty"`
+	Ping               *PingConfig         `json:"ping,omitempty"`
+}
+
+// PingConfig holds ping measurement configuration
+type PingConfig struct {
+	// Mode is "tcp" (connect only) or "handshake" (SOCKS5 greeting + auth, or HTTP CONNECT)
+	Mode          string `json:"mode"`
+	ConnectTarget string `json:"connect_target,omitempty"`
+}
+
+// TCPTargetConfig holds settings for raw TCP targets (target_url "tcp://host:port")
+type TCPTargetConfig struct {
+	Payload string `json:"payload,omitempty"`
+	Expect  string `json:"expect,omitempty"`
+}
+
+// WebSocketConfig holds settings for WebSocket targets (target_url "ws://" or "wss://")
+type WebSocketConfig struct {
+	Payload           string `json:"payload,omitempty"`
+	Messages          int    `json:"messages"`
+	MessageIntervalMs int    `json:"message_interval_ms"`
+	Origin            string `json:"origin,omitempty"`
+}
+
+// UDPBenchmarkConfig holds SOCKS5 UDP ASSOCIATE benchmark configuration
+type UDPBenchmarkConfig struct {
+	Enabled      bool   `json:"enabled"`
+	Target       string `json:"target"`
+	Associations int    `json:"associations"`
+	Datagrams    int    `json:"datagrams"`
+	Payload      string `json:"payload,omitempty"`
+	IntervalMs   int    `json:"interval_ms"`
+
+	// How long to wait for the echo of each datagram before counting it lost
+	DatagramTimeoutMs int `json:"datagram_timeout_ms"`
+}
+
+// StabilityConfig holds long-lived tunnel stability test configuration
+type StabilityConfig struct {
+	Enabled             bool   `json:"enabled"`
+	Target              string `json:"target,omitempty"`
+	Tunnels             int    `json:"tunnels"`
+	DurationMs          int    `json:"duration_ms"`
+	HeartbeatIntervalMs int    `json:"heartbeat_interval_ms"`
+	Heartbeat           string `json:"heartbeat,omitempty"`
+	IdleProbesMs        []int  `json:"idle_probes_ms,omitempty"`
 }
 
 // ResponseValidation holds response validation configuration
@@ -39,9 +218,45 @@ type ValidationCheck struct {
 
 // StatisticsConfig holds statistics configuration
 type StatisticsConfig struct {
-	Percentiles []float64 `json:"percentiles"`
-	Mean        bool      `json:"mean"`
-	Median      bool      `json:"median"`
+	Percentiles []float64         `json:"percentiles"`
+	Mean        bool              `json:"mean"`
+	Median      bool              `json:"median"`
+	Histogram   *HistogramConfig  `json:"histogram,omitempty"`
+	Bootstrap   *BootstrapConfig  `json:"bootstrap,omitempty"`
+	TimeSeries  *TimeSeriesConfig `json:"time_series,omitempty"`
+	Outliers    *OutlierConfig    `json:"outliers,omitempty"`
+}
+
+// OutlierConfig holds outlier detection and robust statistics configuration
+type OutlierConfig struct {
+	Enabled bool `json:"enabled"`
+	// Method is "iqr" (Tukey fences) or "mad" (modified z-score)
+	Method string `json:"method"`
+	// Threshold is the IQR multiplier or the modified z-score cut-off
+	Threshold   float64 `json:"threshold"`
+	TrimPercent float64 `json:"trim_percent"`
+}
+
+// TimeSeriesConfig holds per-interval bucketing of request results
+type TimeSeriesConfig struct {
+	Enabled       bool `json:"enabled"`
+	BucketWidthMs int  `json:"bucket_width_ms"`
+}
+
+// BootstrapConfig holds bootstrap confidence interval configuration
+type BootstrapConfig struct {
+	Enabled   bool    `json:"enabled"`
+	Level     float64 `json:"level"`
+	Resamples int     `json:"resamples"`
+	// Seed is nil when unset so that 0 is a valid, reproducible seed
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+// HistogramConfig holds histogram-based recording configuration
+type HistogramConfig struct {
+	Enabled            bool `json:"enabled"`
+	SignificantFigures int  `json:"significant_figures"`
+	KeepSamples        bool `json:"keep_samples"`
 }
 
 // LoadConfig loads configuration from a JSON file
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// summaryGroup is a statistics block exported as a set of table columns
+type summaryGroup struct {
+	name       string
+	statistics func(*ProxyMetrics) *Statistics
+}
+
+// summaryGroups are the statistics exported per proxy, in column order
+var summaryGroups = []summaryGroup{
+	{"request", func(p *ProxyMetrics) *Statistics { return p.RequestMetrics.Statistics }},
+	{"ping", func(p *ProxyMetrics) *Statistics { return p.PingMetrics.Statistics }},
+	{"derived", func(p *ProxyMetrics) *Statistics { return p.DerivedMetrics.Statistics }},
+	{"failed_request", func(p *ProxyMetrics) *Statistics { return p.RequestMetrics.FailedStatistics }},
+}
+
+// sortedProxies returns the proxies of a result ordered by ID, so tabular
+// outputs are deterministic
+func sortedProxies(result *BenchmarkResult) []*ProxyMetrics {
+	proxies := make([]*ProxyMetrics, len(result.Proxies))
+	copy(proxies, result.Proxies)
+	sort.Slice(proxies, func(i, j int) bool {
+		return proxyID(proxies[i].ProxyString) < proxyID(proxies[j].ProxyString)
+	})
+	return proxies
+}
+
+// percentileKeys returns every percentile reported by any proxy in ascending order
+func percentileKeys(proxies []*ProxyMetrics) []string {
+	seen := make(map[string]bool)
+	for _, proxy := range proxies {
+		for _, group := range summaryGroups {
+			if stat := group.statistics(proxy); stat != nil {
+				for key := range stat.Percentiles {
+					seen[key] = true
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi, _ := strconv.ParseFloat(keys[i], 64)
+		pj, _ := strconv.ParseFloat(keys[j], 64)
+		return pi < pj
+	})
+	return keys
+}
+
+// summaryTable builds one row per proxy with every statistic as a column.
+// Statistics are in the result's display unit, rates in percent.
+func summaryTable(result *BenchmarkResult) ([]string, [][]string) {
+	proxies := sortedProxies(result)
+	percentiles := percentileKeys(proxies)
+
+	header := []string{"proxy", "protocol", "host", "port", "rank", "score",
+		"requests", "successful", "failed", "success_rate",
+		"ping_attempts", "ping_loss_percent", "ping_jitter"}
+	for _, group := range summaryGroups {
+		header = append(header,
+			group.name+"_min", group.name+"_max", group.name+"_mean", group.name+"_median", group.name+"_std_dev")
+		for _, key := range percentiles {
+			header = append(header, group.name+"_p"+key)
+		}
+	}
+
+	scores := make(map[string]*ProxyScore, len(result.Ranking))
+	for _, score := range result.Ranking {
+		scores[score.Proxy] = score
+	}
+
+	rows := make([][]string, 0, len(proxies))
+	for _, proxy := range proxies {
+		protocol, host, port := "", "", ""
+		if parsed, err := ParseProxy(proxy.ProxyString); err == nil {
+			protocol, host, port = parsed.Protocol, parsed.Host, parsed.Port
+		}
+		rank, score := "", ""
+		if s, ok := scores[proxy.ProxyString]; ok {
+			rank, score = strconv.Itoa(s.Rank), formatFloat(s.Score)
+		}
+		request := proxy.RequestMetrics
+		successRate := ""
+		if request.Total > 0 {
+			successRate = formatFloat(float64(request.Successful) / float64(request.Total) * 100)
+		}
+
+		row := []string{proxyID(proxy.ProxyString), protocol, host, port, rank, score,
+			strconv.Itoa(request.Total), strconv.Itoa(request.Successful), strconv.Itoa(request.Failed), successRate,
+			strconv.Itoa(proxy.PingMetrics.Attempts), formatFloat(proxy.PingMetrics.LossPercent), formatFloat(proxy.PingMetrics.Jitter)}
+		for _, group := range summaryGroups {
+			stat := group.statistics(proxy)
+			if stat == nil {
+				row = append(row, make([]string, 5+len(percentiles))...)
+				continue
+			}
+			row = append(row, formatFloat(stat.Min), formatFloat(stat.Max), formatFloat(stat.Mean), formatFloat(stat.Median), formatFloat(stat.StdDev))
+			for _, key := range percentiles {
+				value, ok := stat.Percentiles[key]
+				if !ok {
+					row = append(row, "")
+					continue
+				}
+				row = append(row, formatFloat(value))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// formatFloat formats a value without trailing zeros or exponent
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// SaveCSV saves one row per proxy with all statistic columns
+func (r *Reporter) SaveCSV(result *BenchmarkResult, filepath string) error {
+	header, rows := summaryTable(result)
+	return writeCSV(filepath, header, rows)
+}
+
+// SaveSamplesCSV saves every raw sample as a row of proxy, metric, index and
+// value in microseconds. Samples dropped in favor of histograms are not included.
+func (r *Reporter) SaveSamplesCSV(result *BenchmarkResult, filepath string) error {
+	header := []string{"proxy", "metric", "index", "value_us"}
+	var rows [][]string
+	for _, proxy := range sortedProxies(result) {
+		id := proxyID(proxy.ProxyString)
+		series := []struct {
+			metric string
+			values []int64
+		}{
+			{"request", proxy.RequestMetrics.Times},
+			{"failed_request", proxy.RequestMetrics.FailedTimes},
+			{"ping", proxy.PingMetrics.Times},
+			{"derived", proxy.DerivedMetrics.ProcessingTimes},
+		}
+		for _, s := range series {
+			for i, value := range s.values {
+				rows = append(rows, []string{id, s.metric, strconv.Itoa(i), strconv.FormatInt(value, 10)})
+			}
+		}
+	}
+	return writeCSV(filepath, header, rows)
+}
+
+// writeCSV writes a header and rows to a CSV file
+func writeCSV(filepath string, header []string, rows [][]string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
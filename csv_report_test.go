@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func tabularResult(t *testing.T) *BenchmarkResult {
+	t.Helper()
+	config := &Config{
+		Statistics: StatisticsConfig{Mean: true, Median: true, Percentiles: []float64{99, 50}},
+		Report: ReportConfig{
+			Unit:    "ms",
+			Scoring: &ScoringConfig{Weights: &ScoringWeights{SuccessRate: 1}},
+		},
+	}
+	reporter := NewReporter(config)
+
+	fast := NewMetrics("socks:b.example:1080:user:pass:enabled")
+	fast.AddRequestTime(100*time.Millisecond, true)
+	fast.AddRequestTime(300*time.Millisecond, true)
+	slow := NewMetrics("http:a.example:8080:::enabled")
+	slow.AddRequestTime(500*time.Millisecond, true)
+	slow.AddRequestTime(0, false)
+
+	metrics := map[string]*Metrics{fast.ProxyString: fast, slow.ProxyString: slow}
+	for _, m := range metrics {
+		UpdateMetricsStatistics(m, &config.Statistics)
+	}
+	return reporter.GenerateReport(metrics)
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	return records
+}
+
+func TestSaveCSV(t *testing.T) {
+	result := tabularResult(t)
+	reporter := NewReporter(&Config{})
+	path := filepath.Join(t.TempDir(), "results.csv")
+	if err := reporter.SaveCSV(result, path); err != nil {
+		t.Fatalf("failed to save CSV: %v", err)
+	}
+
+	records := readCSV(t, path)
+	if len(records) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d records", len(records))
+	}
+	header := records[0]
+	column := make(map[string]int, len(header))
+	for i, name := range header {
+		column[name] = i
+	}
+
+	// Percentile columns are in numeric order, proxies sorted by ID
+	if column["request_p50.0"] > column["request_p99.0"] {
+		t.Error("expected p50 column before p99")
+	}
+	if records[1][0] != "http://a.example:8080" || records[2][0] != "socks://b.example:1080" {
+		t.Errorf("expected rows sorted by proxy ID, got %s, %s", records[1][0], records[2][0])
+	}
+	if got := records[2][column["request_mean"]]; got != "200" {
+		t.Errorf("expected request mean 200ms, got %s", got)
+	}
+	if got := records[1][column["success_rate"]]; got != "50" {
+		t.Errorf("expected success rate 50, got %s", got)
+	}
+	for _, record := range records[1:] {
+		if len(record) != len(header) {
+			t.Errorf("expected %d columns, got %d", len(header), len(record))
+		}
+	}
+}
+
+func TestSaveSamplesCSV(t *testing.T) {
+	result := tabularResult(t)
+	path := filepath.Join(t.TempDir(), "samples.csv")
+	if err := NewReporter(&Config{}).SaveSamplesCSV(result, path); err != nil {
+		t.Fatalf("failed to save samples: %v", err)
+	}
+
+	records := readCSV(t, path)
+	// 1 request + 1 failed request for a, 2 requests for b
+	if len(records) != 5 {
+		t.Fatalf("expected header and 4 samples, got %d records", len(records))
+	}
+	if records[1][1] != "request" || records[1][3] != "500000" {
+		t.Errorf("unexpected first sample %v", records[1])
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// Error classes used to break down failures in metrics and reports
+const (
+	ErrorClassTimeout           = "timeout"
+	ErrorClassConnectionRefused = "connection_refused"
+	ErrorClassConnectionReset   = "connection_reset"
+	ErrorClassDNS               = "dns"
+	ErrorClassAuth              = "auth"
+	ErrorClassTLS               = "tls"
+	ErrorClassEOF               = "eof"
+	ErrorClassHTTPStatus        = "http_status"
+	ErrorClassValidation        = "validation"
+	ErrorClassOther             = "other"
+)
+
+// classifyError maps an error to one of the error classes
+func classifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var statusErr *HTTPStatusError
+
+	switch {
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode == http.StatusProxyAuthRequired {
+			return ErrorClassAuth
+		}
+		return ErrorClassHTTPStatus
+	case errors.As(err, &dnsErr):
+		return ErrorClassDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorClassConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrorClassConnectionReset
+	case errors.As(err, &certErr), errors.As(err, &recordErr), errors.As(err, &unknownAuthority), errors.As(err, &hostnameErr):
+		return ErrorClassTLS
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorClassEOF
+	}
+
+	// Proxy libraries report auth and validation problems as plain strings
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "407"), strings.Contains(message, "authentication"), strings.Contains(message, "username/password"):
+		return ErrorClassAuth
+	case strings.Contains(message, "validation failed"), strings.Contains(message, "failed to parse json"):
+		return ErrorClassValidation
+	case strings.Contains(message, "timeout"):
+		return ErrorClassTimeout
+	case strings.Contains(message, "connection refused"):
+		return ErrorClassConnectionRefused
+	case strings.Contains(message, "connection reset"):
+		return ErrorClassConnectionReset
+	}
+
+	return ErrorClassOther
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{context.DeadlineExceeded, ErrorClassTimeout},
+		{fmt.Errorf("dial: %w", syscall.ECONNREFUSED), ErrorClassConnectionRefused},
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, ErrorClassConnectionReset},
+		{&net.DNSError{Err: "no such host", Name: "example.invalid"}, ErrorClassDNS},
+		{fmt.Errorf("read: %w", io.ErrUnexpectedEOF), ErrorClassEOF},
+		{errors.New("proxy refused CONNECT: 407 Proxy Authentication Required"), ErrorClassAuth},
+		{&HTTPStatusError{StatusCode: 407, Status: "407 Proxy Authentication Required"}, ErrorClassAuth},
+		{&HTTPStatusError{StatusCode: 503, Status: "503 Service Unavailable"}, ErrorClassHTTPStatus},
+		{errors.New("validation failed for path 'id': expected number"), ErrorClassValidation},
+		{errors.New("something else"), ErrorClassOther},
+	}
+
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPClientRejectsNon2xx(t *testing.T) {
+	for _, status := range []int{http.StatusProxyAuthRequired, http.StatusBadGateway} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		proxy, err := ParseProxy("http:" + strings.TrimPrefix(server.URL, "http://") + ":::enabled")
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := NewHTTPClient(proxy, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = client.MakeRequest(context.Background(), "http://example.invalid/get")
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+			t.Errorf("expected an HTTPStatusError with %d, got %v", status, err)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GateResult is the outcome of all threshold rules. When Passed is false the
+// process exits with a non-zero status after writing its reports.
+type GateResult struct {
+	Passed   bool              `json:"passed"`
+	Rules    []*GateRuleResult `json:"rules"`
+	Failures []string          `json:"failures,omitempty"`
+}
+
+// GateRuleResult is the outcome of one threshold rule
+type GateRuleResult struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Matched int    `json:"matched"`
+	Met     int    `json:"met"`
+	// Required is the number of matched proxies that had to meet the rule
+	Required int `json:"required"`
+	// Proxies lists the IDs of the matched proxies, Failed those that missed the rule
+	Proxies []string `json:"proxies"`
+	Failed  []string `json:"failed,omitempty"`
+}
+
+// validateThresholds checks the threshold rules for missing or out-of-range values
+func validateThresholds(rules []ThresholdRule) error {
+	for i, rule := range rules {
+		name := thresholdName(rule, i)
+		if rule.MinSuccessRate < 0 || rule.MinSuccessRate > 100 {
+			return fmt.Errorf("threshold %s: min_success_rate must be a percentage", name)
+		}
+		if rule.MaxLatencyMs < 0 {
+			return fmt.Errorf("threshold %s: max_latency_ms must be positive", name)
+		}
+		if rule.Percentile != 0 && rule.MaxLatencyMs == 0 {
+			return fmt.Errorf("threshold %s: percentile needs max_latency_ms", name)
+		}
+		if rule.MaxLatencyMs > 0 && (rule.Percentile <= 0 || rule.Percentile > 100) {
+			return fmt.Errorf("threshold %s: percentile must be between 0 and 100", name)
+		}
+		if rule.MinSuccessRate == 0 && rule.MaxLatencyMs == 0 {
+			return fmt.Errorf("threshold %s: needs min_success_rate or max_latency_ms", name)
+		}
+		if rule.MinProxies < 0 {
+			return fmt.Errorf("threshold %s: min_proxies must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// thresholdName returns the rule's name or a positional one
+func thresholdName(rule ThresholdRule, index int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return fmt.Sprintf("threshold_%d", index+1)
+}
+
+// evaluateGate applies the threshold rules to the proxies' metrics. A rule
+// without min_proxies must be met by every proxy it matches; otherwise at least
+// min_proxies of them must meet it.
+func (r *Reporter) evaluateGate(metrics map[string]*Metrics) *GateResult {
+	if len(r.config.Thresholds) == 0 {
+		return nil
+	}
+
+	proxies := make([]string, 0, len(metrics))
+	for proxyString := range metrics {
+		proxies = append(proxies, proxyString)
+	}
+	sort.Strings(proxies)
+
+	gate := &GateResult{Passed: true}
+	for i, rule := range r.config.Thresholds {
+		result := &GateRuleResult{Name: thresholdName(rule, i)}
+		var reasons []string
+		for _, proxyString := range proxies {
+			if rule.Proxy != "" && !matchesProxy(proxyString, rule.Proxy) {
+				continue
+			}
+			result.Matched++
+			result.Proxies = append(result.Proxies, proxyID(proxyString))
+			if reason := r.checkThreshold(rule, metrics[proxyString]); reason != "" {
+				result.Failed = append(result.Failed, proxyID(proxyString))
+				reasons = append(reasons, fmt.Sprintf("%s %s", proxyID(proxyString), reason))
+				continue
+			}
+			result.Met++
+		}
+
+		result.Required = rule.MinProxies
+		if result.Required == 0 {
+			result.Required = result.Matched
+		}
+		result.Passed = result.Matched > 0 && result.Met >= result.Required
+		gate.Rules = append(gate.Rules, result)
+		if result.Passed {
+			continue
+		}
+
+		gate.Passed = false
+		switch {
+		case result.Matched == 0:
+			gate.Failures = append(gate.Failures, fmt.Sprintf("%s: no proxy matches %q", result.Name, rule.Proxy))
+		case rule.MinProxies > 0:
+			gate.Failures = append(gate.Failures, fmt.Sprintf("%s: only %d of %d proxies met the rule, %d required (%s)",
+				result.Name, result.Met, result.Matched, result.Required, strings.Join(reasons, "; ")))
+		default:
+			gate.Failures = append(gate.Failures, fmt.Sprintf("%s: %s", result.Name, strings.Join(reasons, "; ")))
+		}
+	}
+	return gate
+}
+
+// checkThreshold returns why a proxy misses a rule, or "" if it meets it
+func (r *Reporter) checkThreshold(rule ThresholdRule, m *Metrics) string {
+	request := m.RequestMetrics
+	var reasons []string
+
+	if rule.MinSuccessRate > 0 {
+		successRate := 0.0
+		if request.Total > 0 {
+			successRate = float64(request.Successful) / float64(request.Total) * 100
+		}
+		if request.Total == 0 || successRate < rule.MinSuccessRate {
+			reasons = append(reasons, fmt.Sprintf("success rate %.1f%% < %.1f%%", successRate, rule.MinSuccessRate))
+		}
+	}
+
+	if rule.MaxLatencyMs > 0 {
+		latency, ok := requestPercentile(m, rule.Percentile)
+		switch {
+		case !ok:
+			reasons = append(reasons, fmt.Sprintf("no successful requests for p%g", rule.Percentile))
+		case latency > toMicros(rule.MaxLatencyMs, "ms"):
+			reasons = append(reasons, fmt.Sprintf("p%g %.1fms > %.1fms", rule.Percentile, fromMicros(latency, "ms"), rule.MaxLatencyMs))
+		}
+	}
+
+	return strings.Join(reasons, ", ")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func gateMetrics() map[string]*Metrics {
+	good := NewMetrics("http:good:8080:::enabled")
+	for i := 0; i < 10; i++ {
+		good.AddRequestTime(100*time.Millisecond, true)
+	}
+	bad := NewMetrics("socks:bad:1080:::enabled")
+	bad.AddRequestTime(900*time.Millisecond, true)
+	for i := 0; i < 9; i++ {
+		bad.AddRequestTime(0, false)
+	}
+	return map[string]*Metrics{good.ProxyString: good, bad.ProxyString: bad}
+}
+
+func TestGateEveryProxy(t *testing.T) {
+	reporter := NewReporter(&Config{Thresholds: []ThresholdRule{
+		{Name: "reliable", MinSuccessRate: 95, Percentile: 95, MaxLatencyMs: 500},
+	}})
+
+	gate := reporter.evaluateGate(gateMetrics())
+	if gate.Passed {
+		t.Fatal("expected gate to fail")
+	}
+	rule := gate.Rules[0]
+	if rule.Matched != 2 || rule.Met != 1 || len(rule.Failed) != 1 || rule.Failed[0] != "socks://bad:1080" {
+		t.Errorf("expected only socks://bad:1080 to fail, got %+v", rule)
+	}
+	if len(gate.Failures) != 1 || !strings.Contains(gate.Failures[0], "success rate 10.0% < 95.0%") || !strings.Contains(gate.Failures[0], "p95 900.0ms > 500.0ms") {
+		t.Errorf("unexpected failure explanation: %v", gate.Failures)
+	}
+}
+
+func TestGateMinProxies(t *testing.T) {
+	reporter := NewReporter(&Config{Thresholds: []ThresholdRule{
+		{MinProxies: 1, MinSuccessRate: 95},
+		{Proxy: "good:8080", MinSuccessRate: 99},
+	}})
+
+	gate := reporter.evaluateGate(gateMetrics())
+	if !gate.Passed {
+		t.Errorf("expected gate to pass, got failures %v", gate.Failures)
+	}
+	if gate.Rules[1].Matched != 1 {
+		t.Errorf("expected per-proxy rule to match one proxy, got %d", gate.Rules[1].Matched)
+	}
+
+	reporter.config.Thresholds = []ThresholdRule{{MinProxies: 2, MinSuccessRate: 95}}
+	if gate := reporter.evaluateGate(gateMetrics()); gate.Passed {
+		t.Error("expected gate requiring 2 healthy proxies to fail")
+	}
+}
+
+func TestGateUnmatchedProxy(t *testing.T) {
+	reporter := NewReporter(&Config{Thresholds: []ThresholdRule{{Proxy: "http://missing:1", MinSuccessRate: 50}}})
+	gate := reporter.evaluateGate(gateMetrics())
+	if gate.Passed || !strings.Contains(gate.Failures[0], "no proxy matches") {
+		t.Errorf("expected unmatched rule to fail, got %+v", gate)
+	}
+}
+
+func TestValidateThresholds(t *testing.T) {
+	if err := validateThresholds([]ThresholdRule{{Name: "empty"}}); err == nil {
+		t.Error("expected error for rule without conditions")
+	}
+	if err := validateThresholds([]ThresholdRule{{MaxLatencyMs: 100}}); err == nil {
+		t.Error("expected error for latency rule without percentile")
+	}
+	if err := validateThresholds([]ThresholdRule{{Percentile: 95}}); err == nil {
+		t.Error("expected error for percentile without max_latency_ms")
+	}
+	if err := validateThresholds([]ThresholdRule{{MinSuccessRate: 90, Percentile: 95}}); err == nil {
+		t.Error("expected error for percentile without max_latency_ms next to min_success_rate")
+	}
+	if err := validateThresholds([]ThresholdRule{{MinSuccessRate: 90, Percentile: 95, MaxLatencyMs: -1}}); err == nil {
+		t.Error("expected error for negative max_latency_ms")
+	}
+	if err := validateThresholds([]ThresholdRule{{MinSuccessRate: 90, Percentile: 95, MaxLatencyMs: 800}}); err != nil {
+		t.Errorf("expected combined rule to be valid, got %v", err)
+	}
+}
@@ -6,5 +6,8 @@ toolchain go1.24.6
 
 require (
 	github.com/montanaflynn/stats v0.7.1
+	go.etcd.io/bbolt v1.3.11
 	golang.org/x/net v0.43.0
 )
+
+require golang.org/x/sys v0.35.0 // indirect
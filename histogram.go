@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// Histogram is an HDR-style log-linear histogram of non-negative microsecond
+// values. Values are exact below 2^(precisionBits+1) and otherwise kept with a
+// relative error of at most 2^-precisionBits, so memory is bounded by the value
+// range rather than the number of samples. Histograms with the same precision
+// can be merged.
+type Histogram struct {
+	significantFigures int
+	precisionBits      uint
+	counts             map[int]int64
+	total              int64
+	min                int64
+	max                int64
+	sum                float64
+	sumSquares         float64
+}
+
+// HistogramBucket is a non-empty histogram bucket in serialized form
+type HistogramBucket struct {
+	Value int64 `json:"value"`
+	Count int64 `json:"count"`
+}
+
+// histogramJSON is the serialized form of a Histogram
+type histogramJSON struct {
+	Unit               string            `json:"unit"`
+	SignificantFigures int               `json:"significant_figures"`
+	TotalCount         int64             `json:"total_count"`
+	Min                int64             `json:"min"`
+	Max                int64             `json:"max"`
+	Sum                float64           `json:"sum"`
+	SumSquares         float64           `json:"sum_squares"`
+	Buckets            []HistogramBucket `json:"buckets"`
+}
+
+// NewHistogram creates a histogram keeping the given number of significant
+// decimal figures (1-5)
+func NewHistogram(significantFigures int) *Histogram {
+	if significantFigures < 1 {
+		significantFigures = 1
+	}
+	if significantFigures > 5 {
+		significantFigures = 5
+	}
+
+	return &Histogram{
+		significantFigures: significantFigures,
+		precisionBits:      uint(math.Ceil(math.Log2(math.Pow10(significantFigures)))),
+		counts:             make(map[int]int64),
+	}
+}
+
+// Record adds a single value in microseconds
+func (h *Histogram) Record(value int64) {
+	h.RecordN(value, 1)
+}
+
+// RecordDuration adds a duration at microsecond resolution
+func (h *Histogram) RecordDuration(duration time.Duration) {
+	h.Record(duration.Microseconds())
+}
+
+// RecordN adds value count times. Negative values are recorded as 0.
+func (h *Histogram) RecordN(value, count int64) {
+	if count <= 0 {
+		return
+	}
+	if value < 0 {
+		value = 0
+	}
+
+	if h.total == 0 || value < h.min {
+		h.min = value
+	}
+	if h.total == 0 || value > h.max {
+		h.max = value
+	}
+
+	h.counts[h.bucketIndex(value)] += count
+	h.total += count
+	h.sum += float64(value) * float64(count)
+	h.sumSquares += float64(value) * float64(value) * float64(count)
+}
+
+// Merge adds all values of other into h. Histograms of a different precision are
+// merged by re-recording the other histogram's bucket midpoints.
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.total == 0 {
+		return
+	}
+
+	if other.precisionBits != h.precisionBits {
+		for index, count := range other.counts {
+			h.RecordN(other.bucketMidpoint(index), count)
+		}
+		return
+	}
+
+	if h.total == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if h.total == 0 || other.max > h.max {
+		h.max = other.max
+	}
+	for index, count := range other.counts {
+		h.counts[index] += count
+	}
+	h.total += other.total
+	h.sum += other.sum
+	h.sumSquares += other.sumSquares
+}
+
+// Shifted returns a copy of the histogram with every value moved by offset.
+// Values that would become negative are recorded as 0.
+func (h *Histogram) Shifted(offset int64) *Histogram {
+	shifted := NewHistogram(h.significantFigures)
+	for index, count := range h.counts {
+		shifted.RecordN(h.clamp(h.bucketMidpoint(index))+offset, count)
+	}
+	return shifted
+}
+
+// Count returns the number of recorded values
+func (h *Histogram) Count() int64 {
+	return h.total
+}
+
+// Min returns the smallest recorded value
+func (h *Histogram) Min() int64 {
+	return h.min
+}
+
+// Max returns the largest recorded value
+func (h *Histogram) Max() int64 {
+	return h.max
+}
+
+// Sum returns the exact sum of the recorded values
+func (h *Histogram) Sum() float64 {
+	return h.sum
+}
+
+// Mean returns the exact mean of the recorded values
+func (h *Histogram) Mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / float64(h.total)
+}
+
+// StdDev returns the exact population standard deviation of the recorded values
+func (h *Histogram) StdDev() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	mean := h.Mean()
+	variance := h.sumSquares/float64(h.total) - mean*mean
+	if variance < 0 {
+		return 0
+	}
+	return math.Sqrt(variance)
+}
+
+// ValueAtPercentile returns the value below which the given percentage of
+// recorded values fall, within the histogram's precision
+func (h *Histogram) ValueAtPercentile(percentile float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+	if percentile <= 0 {
+		return h.min
+	}
+	if percentile >= 100 {
+		return h.max
+	}
+
+	rank := int64(math.Ceil(percentile / 100 * float64(h.total)))
+	var seen int64
+	for _, index := range h.sortedIndexes() {
+		seen += h.counts[index]
+		if seen >= rank {
+			return h.clamp(h.bucketMidpoint(index))
+		}
+	}
+	return h.max
+}
+
+// CountAtOrBelow returns how many recorded values are at or below value. Like
+// ValueAtPercentile it places each bucket's values at its clamped midpoint.
+func (h *Histogram) CountAtOrBelow(value int64) int64 {
+	var count int64
+	for _, index := range h.sortedIndexes() {
+		if h.clamp(h.bucketMidpoint(index)) > value {
+			break
+		}
+		count += h.counts[index]
+	}
+	return count
+}
+
+// Buckets returns the non-empty buckets in ascending order, each keyed by the
+// lowest value it holds
+func (h *Histogram) Buckets() []HistogramBucket {
+	buckets := make([]HistogramBucket, 0, len(h.counts))
+	for _, index := range h.sortedIndexes() {
+		low, _ := h.bucketRange(index)
+		buckets = append(buckets, HistogramBucket{Value: low, Count: h.counts[index]})
+	}
+	return buckets
+}
+
+// MarshalJSON serializes the histogram with sparse buckets
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	return json.Marshal(histogramJSON{
+		Unit:               "us",
+		SignificantFigures: h.significantFigures,
+		TotalCount:         h.total,
+		Min:                h.min,
+		Max:                h.max,
+		Sum:                h.sum,
+		SumSquares:         h.sumSquares,
+		Buckets:            h.Buckets(),
+	})
+}
+
+// UnmarshalJSON restores a histogram serialized by MarshalJSON
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	var serialized histogramJSON
+	if err := json.Unmarshal(data, &serialized); err != nil {
+		return err
+	}
+	if serialized.Unit != "" && serialized.Unit != "us" {
+		return fmt.Errorf("unsupported histogram unit %q", serialized.Unit)
+	}
+
+	*h = *NewHistogram(serialized.SignificantFigures)
+	for _, bucket := range serialized.Buckets {
+		h.counts[h.bucketIndex(bucket.Value)] += bucket.Count
+	}
+	h.total = serialized.TotalCount
+	h.min = serialized.Min
+	h.max = serialized.Max
+	h.sum = serialized.Sum
+	h.sumSquares = serialized.SumSquares
+	return nil
+}
+
+// bucketIndex maps a value to its bucket
+func (h *Histogram) bucketIndex(value int64) int {
+	shift := bits.Len64(uint64(value)) - int(h.precisionBits+1)
+	if shift <= 0 {
+		return int(value)
+	}
+	return shift<<h.precisionBits + int(value>>uint(shift))
+}
+
+// bucketRange returns the lowest and highest value held by a bucket
+func (h *Histogram) bucketRange(index int) (int64, int64) {
+	if index < 1<<(h.precisionBits+1) {
+		return int64(index), int64(index)
+	}
+	shift := uint(index>>h.precisionBits) - 1
+	mantissa := int64(index - int(shift)<<h.precisionBits)
+	return mantissa << shift, (mantissa+1)<<shift - 1
+}
+
+// bucketMidpoint returns the value representing a bucket
+func (h *Histogram) bucketMidpoint(index int) int64 {
+	low, high := h.bucketRange(index)
+	return low + (high-low)/2
+}
+
+// clamp limits a bucket value to the recorded range
+func (h *Histogram) clamp(value int64) int64 {
+	if value < h.min {
+		return h.min
+	}
+	if value > h.max {
+		return h.max
+	}
+	return value
+}
+
+// sortedIndexes returns the non-empty bucket indexes in ascending order
+func (h *Histogram) sortedIndexes() []int {
+	indexes := make([]int, 0, len(h.counts))
+	for index := range h.counts {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
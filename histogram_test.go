@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHistogramPercentiles(t *testing.T) {
+	h := NewHistogram(3)
+	for v := int64(1); v <= 100000; v++ {
+		h.Record(v)
+	}
+
+	if h.Count() != 100000 || h.Min() != 1 || h.Max() != 100000 {
+		t.Fatalf("unexpected count/min/max: %d/%d/%d", h.Count(), h.Min(), h.Max())
+	}
+
+	for _, p := range []float64{50, 90, 99, 99.9} {
+		want := p / 100 * 100000
+		got := float64(h.ValueAtPercentile(p))
+		if math.Abs(got-want)/want > 0.001 {
+			t.Errorf("p%.1f: got %v, want %v within 0.1%%", p, got, want)
+		}
+	}
+	if mean := h.Mean(); mean != 50000.5 {
+		t.Errorf("expected exact mean 50000.5, got %v", mean)
+	}
+}
+
+func TestHistogramBoundedMemory(t *testing.T) {
+	h := NewHistogram(3)
+	for i := int64(0); i < 1000000; i++ {
+		h.Record(i * 997 % 60000000)
+	}
+	if buckets := len(h.Buckets()); buckets > 20000 {
+		t.Errorf("expected bounded bucket count, got %d", buckets)
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a := NewHistogram(3)
+	b := NewHistogram(3)
+	for v := int64(1); v <= 1000; v++ {
+		a.Record(v)
+		b.Record(v + 1000)
+	}
+
+	a.Merge(b)
+	if a.Count() != 2000 || a.Min() != 1 || a.Max() != 2000 {
+		t.Errorf("unexpected merged count/min/max: %d/%d/%d", a.Count(), a.Min(), a.Max())
+	}
+	if median := a.ValueAtPercentile(50); median != 1000 {
+		t.Errorf("expected merged median 1000, got %d", median)
+	}
+}
+
+func TestHistogramJSONRoundTrip(t *testing.T) {
+	h := NewHistogram(2)
+	h.RecordDuration(1500 * time.Microsecond)
+	h.RecordDuration(250 * time.Millisecond)
+	h.RecordDuration(3 * time.Second)
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	restored := &Histogram{}
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	for _, p := range []float64{0, 50, 100} {
+		if restored.ValueAtPercentile(p) != h.ValueAtPercentile(p) {
+			t.Errorf("p%v differs after round trip: %d vs %d", p, restored.ValueAtPercentile(p), h.ValueAtPercentile(p))
+		}
+	}
+	if restored.Count() != 3 || restored.Mean() != h.Mean() {
+		t.Errorf("count/mean differ after round trip")
+	}
+}
+
+func TestHistogramShifted(t *testing.T) {
+	h := NewHistogram(3)
+	h.Record(100)
+	h.Record(5000)
+
+	shifted := h.Shifted(-200)
+	if shifted.Min() != 0 {
+		t.Errorf("expected negative values to clamp to 0, got %d", shifted.Min())
+	}
+	if shifted.Max() < 4790 || shifted.Max() > 4810 {
+		t.Errorf("expected max near 4800, got %d", shifted.Max())
+	}
+}
+
+func TestMetricsHistogramWithoutSamples(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.EnableHistograms(3, false)
+	metrics.AddRequestTime(120*time.Millisecond, true)
+	metrics.AddRequestTime(180*time.Millisecond, true)
+	metrics.AddPingTime(20 * time.Millisecond)
+
+	if len(metrics.RequestMetrics.Times) != 0 {
+		t.Errorf("expected no raw samples, got %d", len(metrics.RequestMetrics.Times))
+	}
+
+	metrics.DeriveHistogram()
+	config := &StatisticsConfig{Mean: true, Percentiles: []float64{50}}
+	UpdateMetricsStatistics(metrics, config)
+
+	if mean := metrics.RequestMetrics.Statistics.Mean; mean != 150000 {
+		t.Errorf("expected request mean 150000us from histogram, got %v", mean)
+	}
+	if mean := metrics.DerivedMetrics.Statistics.Mean; math.Abs(mean-110000) > 500 {
+		t.Errorf("expected derived mean near 110000us, got %v", mean)
+	}
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// historyRunsBucket holds one RunRecord per run, keyed by timestamp and run ID
+var historyRunsBucket = []byte("runs")
+
+// History metric names. Latencies are in milliseconds, the success rate in
+// percent and the score on its 0-100 scale.
+const (
+	HistorySuccessRate = "success_rate"
+	HistoryMean        = "mean"
+	HistoryP50         = "p50"
+	HistoryP95         = "p95"
+	HistoryP99         = "p99"
+	HistoryJitter      = "jitter"
+	HistoryScore       = "score"
+)
+
+// historyMetrics lists the recorded metrics
+var historyMetrics = []string{HistorySuccessRate, HistoryMean, HistoryP50, HistoryP95, HistoryP99, HistoryJitter, HistoryScore}
+
+// RunRecord is the summary of one run kept in the history store
+type RunRecord struct {
+	RunID     string         `json:"run_id"`
+	Timestamp time.Time      `json:"timestamp"`
+	Target    string         `json:"target,omitempty"`
+	Proxies   []*ProxyRecord `json:"proxies"`
+}
+
+// ProxyRecord holds the history metrics of one proxy in a run
+type ProxyRecord struct {
+	Proxy   string             `json:"proxy"`
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// TrendPoint is a proxy's metric value in one run
+type TrendPoint struct {
+	RunID     string    `json:"run_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// Degradation describes a proxy whose metric got steadily worse against a
+// target. Slope is the change per run as a percentage of the mean over the
+// window, positive meaning worse; RSquared is how well a straight line fits the
+// values.
+type Degradation struct {
+	Proxy    string  `json:"proxy"`
+	Target   string  `json:"target,omitempty"`
+	Metric   string  `json:"metric"`
+	Runs     int     `json:"runs"`
+	First    float64 `json:"first"`
+	Last     float64 `json:"last"`
+	Slope    float64 `json:"slope_percent_per_run"`
+	RSquared float64 `json:"r_squared"`
+}
+
+// HistoryStore is an embedded bbolt database of run summaries
+type HistoryStore struct {
+	db *bolt.DB
+}
+
+// OpenHistory opens the history store at path, creating it if needed. A
+// read-only store must exist and does not block concurrent readers.
+func OpenHistory(path string, readOnly bool) (*HistoryStore, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+	return &HistoryStore{db: db}, nil
+}
+
+// Close closes the store
+func (h *HistoryStore) Close() error {
+	return h.db.Close()
+}
+
+// Append stores a run
+func (h *HistoryStore) Append(record *RunRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	key := record.Timestamp.UTC().Format("2006-01-02T15:04:05.000000000Z") + "/" + record.RunID
+
+	return h.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(historyRunsBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), data)
+	})
+}
+
+// Runs returns the stored runs oldest first, limited to the most recent limit
+// runs if limit is positive
+func (h *HistoryStore) Runs(limit int) ([]*RunRecord, error) {
+	var runs []*RunRecord
+	err := h.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(historyRunsBucket)
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+			if limit > 0 && len(runs) == limit {
+				break
+			}
+			var record RunRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return fmt.Errorf("corrupt history entry %s: %w", key, err)
+			}
+			runs = append(runs, &record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	return runs, nil
+}
+
+// Trend returns a proxy's metric over the runs it took part in, oldest first.
+// The proxy may be given as its ID or as host:port. Latencies against different
+// targets are not comparable, so without a target the runs must all share one.
+func (h *HistoryStore) Trend(proxy, metric, target string, limit int) ([]*TrendPoint, error) {
+	runs, err := h.Runs(0)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []*TrendPoint
+	targets := make(map[string]bool)
+	for _, run := range runs {
+		if target != "" && !matchesTarget(run.Target, target) {
+			continue
+		}
+		for _, record := range run.Proxies {
+			if !matchesProxyID(record.Proxy, proxy) {
+				continue
+			}
+			if value, ok := record.Metrics[metric]; ok {
+				points = append(points, &TrendPoint{RunID: run.RunID, Timestamp: run.Timestamp, Value: value})
+				targets[redactURL(run.Target)] = true
+			}
+		}
+	}
+	if len(targets) > 1 {
+		return nil, fmt.Errorf("%s was benchmarked against %d targets, select one with -target", proxy, len(targets))
+	}
+	if limit > 0 && len(points) > limit {
+		points = points[len(points)-limit:]
+	}
+	return points, nil
+}
+
+// Degrading returns the proxies whose metric got worse over their last window
+// runs by at least minSlope percent per run, along a line fitting the values
+// with an R² of at least minRSquared. Proxies with fewer than minRuns values
+// in the window are skipped. Each proxy is judged per target, and only against
+// target if it is set. Results are ordered by slope, steepest first.
+func (h *HistoryStore) Degrading(metric, target string, window, minRuns int, minSlope, minRSquared float64) ([]*Degradation, error) {
+	runs, err := h.Runs(0)
+	if err != nil {
+		return nil, err
+	}
+
+	type seriesKey struct{ proxy, target string }
+	values := make(map[seriesKey][]float64)
+	for _, run := range runs {
+		if target != "" && !matchesTarget(run.Target, target) {
+			continue
+		}
+		for _, record := range run.Proxies {
+			if value, ok := record.Metrics[metric]; ok {
+				key := seriesKey{proxy: record.Proxy, target: redactURL(run.Target)}
+				values[key] = append(values[key], value)
+			}
+		}
+	}
+
+	var degraded []*Degradation
+	for key, series := range values {
+		if window > 0 && len(series) > window {
+			series = series[len(series)-window:]
+		}
+		if len(series) < minRuns {
+			continue
+		}
+
+		slope, rSquared := linearTrend(series)
+		mean := 0.0
+		for _, v := range series {
+			mean += v
+		}
+		mean /= float64(len(series))
+		if mean == 0 {
+			continue
+		}
+
+		relative := slope / math.Abs(mean) * 100
+		if historyHigherIsBetter(metric) {
+			relative = -relative
+		}
+		if relative < minSlope || rSquared < minRSquared {
+			continue
+		}
+		degraded = append(degraded, &Degradation{
+			Proxy:    key.proxy,
+			Target:   key.target,
+			Metric:   metric,
+			Runs:     len(series),
+			First:    series[0],
+			Last:     series[len(series)-1],
+			Slope:    relative,
+			RSquared: rSquared,
+		})
+	}
+
+	sort.Slice(degraded, func(i, j int) bool {
+		if degraded[i].Slope != degraded[j].Slope {
+			return degraded[i].Slope > degraded[j].Slope
+		}
+		if degraded[i].Proxy != degraded[j].Proxy {
+			return degraded[i].Proxy < degraded[j].Proxy
+		}
+		return degraded[i].Target < degraded[j].Target
+	})
+	return degraded, nil
+}
+
+// linearTrend fits values against their index by least squares and returns the
+// slope and the coefficient of determination
+func linearTrend(values []float64) (float64, float64) {
+	n := float64(len(values))
+	var sumX, sumY, sumXY, sumXX, sumYY float64
+	for i, y := range values {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+		sumYY += y * y
+	}
+
+	varianceX := n*sumXX - sumX*sumX
+	varianceY := n*sumYY - sumY*sumY
+	if varianceX == 0 {
+		return 0, 0
+	}
+	covariance := n*sumXY - sumX*sumY
+	slope := covariance / varianceX
+	if varianceY == 0 {
+		return slope, 0
+	}
+	return slope, covariance * covariance / (varianceX * varianceY)
+}
+
+// historyHigherIsBetter reports whether a larger value of metric is an improvement
+func historyHigherIsBetter(metric string) bool {
+	return metric == HistorySuccessRate || metric == HistoryScore
+}
+
+// matchesProxyID reports whether key identifies a proxy ID, either as the ID
+// itself or as its host:port
+func matchesProxyID(id, key string) bool {
+	if id == key {
+		return true
+	}
+	_, address, ok := strings.Cut(id, "://")
+	return ok && address == key
+}
+
+// matchesTarget reports whether a recorded run target is target. Targets are
+// recorded with credentials redacted, so both sides are compared redacted.
+func matchesTarget(recorded, target string) bool {
+	return redactURL(recorded) == redactURL(target)
+}
+
+// newRunRecord summarizes a result for the history store
+func newRunRecord(result *BenchmarkResult, target string) *RunRecord {
+	record := &RunRecord{
+		RunID:     result.RunID,
+		Timestamp: result.Timestamp,
+		Target:    redactURL(target),
+	}
+
+	millis := func(v float64) float64 {
+		return fromMicros(toMicros(v, result.Unit), "ms")
+	}
+	scores := make(map[string]float64, len(result.Ranking))
+	for _, score := range result.Ranking {
+		scores[score.Proxy] = score.Score
+	}
+
+	for _, proxy := range sortedProxies(result) {
+		metrics := make(map[string]float64)
+		request := proxy.RequestMetrics
+		if request.Total > 0 {
+			metrics[HistorySuccessRate] = float64(request.Successful) / float64(request.Total) * 100
+		}
+		if stat := request.Statistics; stat != nil {
+			metrics[HistoryMean] = millis(stat.Mean)
+			for key, name := range map[string]string{"50.0": HistoryP50, "95.0": HistoryP95, "99.0": HistoryP99} {
+				if value, ok := stat.Percentiles[key]; ok {
+					metrics[name] = millis(value)
+				}
+			}
+		}
+		if proxy.PingMetrics.Attempts > 0 {
+			metrics[HistoryJitter] = millis(proxy.PingMetrics.Jitter)
+		}
+		if score, ok := scores[proxy.ProxyString]; ok {
+			metrics[HistoryScore] = score
+		}
+		record.Proxies = append(record.Proxies, &ProxyRecord{Proxy: proxyID(proxy.ProxyString), Metrics: metrics})
+	}
+	return record
+}
+
+// validateHistoryMetric checks that metric is recorded in the history
+func validateHistoryMetric(metric string) error {
+	if !containsString(historyMetrics, metric) {
+		return fmt.Errorf("unknown metric %q (expected one of %s)", metric, strings.Join(historyMetrics, ", "))
+	}
+	return nil
+}
+
+// writeRuns prints the stored runs
+func writeRuns(w io.Writer, runs []*RunRecord) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "RUN ID\tTIMESTAMP\tPROXIES\tMEAN SUCCESS RATE\tTARGET")
+	for _, run := range runs {
+		var total float64
+		var count int
+		for _, proxy := range run.Proxies {
+			if value, ok := proxy.Metrics[HistorySuccessRate]; ok {
+				total += value
+				count++
+			}
+		}
+		successRate := "-"
+		if count > 0 {
+			successRate = fmt.Sprintf("%.2f%%", total/float64(count))
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\n",
+			run.RunID, run.Timestamp.Format("2006-01-02 15:04:05"), len(run.Proxies), successRate, run.Target)
+	}
+	return table.Flush()
+}
+
+// writeTrend prints a proxy's metric over time with the change from the previous run
+func writeTrend(w io.Writer, points []*TrendPoint) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "RUN ID\tTIMESTAMP\tVALUE\tCHANGE")
+	for i, point := range points {
+		change := ""
+		if i > 0 {
+			change = fmt.Sprintf("%+.2f", point.Value-points[i-1].Value)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%.2f\t%s\n", point.RunID, point.Timestamp.Format("2006-01-02 15:04:05"), point.Value, change)
+	}
+	return table.Flush()
+}
+
+// writeDegradations prints the degrading proxies
+func writeDegradations(w io.Writer, degraded []*Degradation) error {
+	if len(degraded) == 0 {
+		fmt.Fprintln(w, "No degrading proxies")
+		return nil
+	}
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PROXY\tTARGET\tMETRIC\tRUNS\tFIRST\tLAST\tWORSE PER RUN\tR²")
+	for _, d := range degraded {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%.2f\t%.2f\t%.1f%%\t%.2f\n", d.Proxy, d.Target, d.Metric, d.Runs, d.First, d.Last, d.Slope, d.RSquared)
+	}
+	return table.Flush()
+}
+
+// runHistory implements the history command and returns the process exit code.
+// The degrading query exits with 2 if any proxy is degrading.
+func runHistory(args []string) int {
+	usage := "Usage: proxy-benchmark history <runs|trend|degrading> [flags]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+
+	query := args[0]
+	flags := flag.NewFlagSet("history "+query, flag.ContinueOnError)
+	dbPath := flags.String("db", "history.db", "Path to the history store")
+	limit := flags.Int("limit", 0, "Only show the most recent runs (runs, trend; 0 for all)")
+	proxy := flags.String("proxy", "", "Proxy ID or host:port (trend)")
+	target := flags.String("target", "", "Only use runs against this target URL (trend, degrading)")
+	metric := flags.String("metric", HistoryP95, "Metric: "+strings.Join(historyMetrics, ", "))
+	window := flags.Int("window", 10, "Number of most recent runs per proxy to fit (degrading)")
+	minRuns := flags.Int("min-runs", 5, "Minimum runs a proxy needs to be judged (degrading)")
+	minSlope := flags.Float64("min-slope", 2, "Minimum worsening per run, in percent of the mean (degrading)")
+	minRSquared := flags.Float64("min-r2", 0.6, "Minimum R² of the linear fit (degrading)")
+	jsonOutput := flags.Bool("json", false, "Print JSON instead of a table")
+	if err := flags.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 1
+	}
+
+	if query != "runs" && query != "trend" && query != "degrading" {
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+	if err := validateHistoryMetric(*metric); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid history query: %v\n", err)
+		return 1
+	}
+	if query == "trend" && *proxy == "" {
+		fmt.Fprintln(os.Stderr, "Invalid history query: -proxy is required")
+		return 1
+	}
+	if _, err := os.Stat(*dbPath); err != nil {
+		fmt.Fprintf(os.Stderr, "History store not found: %s\n", *dbPath)
+		return 1
+	}
+
+	store, err := OpenHistory(*dbPath, true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	var result interface{}
+	var write func() error
+	code := 0
+	switch query {
+	case "runs":
+		runs, err := store.Runs(*limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read history: %v\n", err)
+			return 1
+		}
+		result, write = runs, func() error { return writeRuns(os.Stdout, runs) }
+	case "trend":
+		points, err := store.Trend(*proxy, *metric, *target, *limit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read history: %v\n", err)
+			return 1
+		}
+		result, write = points, func() error { return writeTrend(os.Stdout, points) }
+	case "degrading":
+		degraded, err := store.Degrading(*metric, *target, *window, *minRuns, *minSlope, *minRSquared)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read history: %v\n", err)
+			return 1
+		}
+		result, write = degraded, func() error { return writeDegradations(os.Stdout, degraded) }
+		if len(degraded) > 0 {
+			code = 2
+		}
+	}
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else if err := write(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return code
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/montanaflynn/stats"
+)
+
+// SVG chart layout
+const (
+	chartWidth      = 760
+	chartMarginLeft = 170
+	chartMargin     = 30
+	histogramBins   = 20
+)
+
+// chartColors is the palette proxies are drawn with, in summary table order
+var chartColors = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+// htmlReport is the data rendered by htmlTemplate
+type htmlReport struct {
+	Generated string
+	Unit      string
+	Rows      []htmlSummaryRow
+	Charts    []htmlChart
+}
+
+// htmlSummaryRow is one proxy in the summary table
+type htmlSummaryRow struct {
+	Rank        string
+	Proxy       string
+	Color       string
+	Score       string
+	Requests    string
+	SuccessRate string
+	Mean        string
+	Median      string
+	P95         string
+	Jitter      string
+}
+
+// htmlChart is a titled inline SVG chart
+type htmlChart struct {
+	Title string
+	SVG   template.HTML
+}
+
+// htmlProxy pairs a proxy's metrics with its display ID and chart color
+type htmlProxy struct {
+	id      string
+	color   string
+	metrics *ProxyMetrics
+}
+
+// GenerateHTML renders a benchmark result as a single self-contained HTML page
+// with a sortable summary table and inline SVG charts
+func (r *Reporter) GenerateHTML(result *BenchmarkResult) (string, error) {
+	proxies := htmlProxies(result)
+	report := &htmlReport{
+		Generated: result.Timestamp.Format("2006-01-02 15:04:05 MST"),
+		Unit:      result.Unit,
+	}
+
+	scores := make(map[string]*ProxyScore, len(result.Ranking))
+	for _, score := range result.Ranking {
+		scores[score.Proxy] = score
+	}
+	for _, proxy := range proxies {
+		report.Rows = append(report.Rows, htmlRow(proxy, scores[proxy.metrics.ProxyString], result.Unit))
+	}
+
+	if svg := percentileChart(proxies); svg != "" {
+		report.Charts = append(report.Charts, htmlChart{Title: "Request latency percentiles (" + result.Unit + ")", SVG: svg})
+	}
+	if svg := boxPlotChart(proxies, result.Unit); svg != "" {
+		report.Charts = append(report.Charts, htmlChart{Title: "Request latency distribution (" + result.Unit + ")", SVG: svg})
+	}
+	if svg := timeSeriesChart(proxies); svg != "" {
+		report.Charts = append(report.Charts, htmlChart{Title: "Median request latency over time (" + result.Unit + ")", SVG: svg})
+	}
+	for _, proxy := range proxies {
+		if svg := histogramChart(proxy, result.Unit); svg != "" {
+			report.Charts = append(report.Charts, htmlChart{Title: "Request latency histogram: " + proxy.id, SVG: svg})
+		}
+	}
+
+	var page strings.Builder
+	if err := htmlTemplate.Execute(&page, report); err != nil {
+		return "", err
+	}
+	return page.String(), nil
+}
+
+// SaveHTML saves the benchmark result as an HTML report
+func (r *Reporter) SaveHTML(result *BenchmarkResult, filepath string) error {
+	page, err := r.GenerateHTML(result)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath, []byte(page), 0644)
+}
+
+// htmlProxies orders proxies by rank, or by proxy string without a ranking, and
+// assigns each a chart color
+func htmlProxies(result *BenchmarkResult) []*htmlProxy {
+	rank := make(map[string]int, len(result.Ranking))
+	for _, score := range result.Ranking {
+		rank[score.Proxy] = score.Rank
+	}
+
+	metrics := make([]*ProxyMetrics, len(result.Proxies))
+	copy(metrics, result.Proxies)
+	sort.Slice(metrics, func(i, j int) bool {
+		ri, rj := rank[metrics[i].ProxyString], rank[metrics[j].ProxyString]
+		if ri != rj {
+			return ri < rj
+		}
+		return metrics[i].ProxyString < metrics[j].ProxyString
+	})
+
+	proxies := make([]*htmlProxy, len(metrics))
+	for i, m := range metrics {
+		proxies[i] = &htmlProxy{
+			id:      proxyID(m.ProxyString),
+			color:   chartColors[i%len(chartColors)],
+			metrics: m,
+		}
+	}
+	return proxies
+}
+
+// htmlRow builds the summary table row of a proxy
+func htmlRow(proxy *htmlProxy, score *ProxyScore, unit string) htmlSummaryRow {
+	request := proxy.metrics.RequestMetrics
+	row := htmlSummaryRow{
+		Rank:        "-",
+		Proxy:       proxy.id,
+		Color:       proxy.color,
+		Score:       "-",
+		Requests:    fmt.Sprintf("%d/%d", request.Successful, request.Total),
+		SuccessRate: "0.0",
+		Mean:        "-",
+		Median:      "-",
+		P95:         "-",
+		Jitter:      formatValue(proxy.metrics.PingMetrics.Jitter),
+	}
+	if score != nil {
+		row.Rank = strconv.Itoa(score.Rank)
+		row.Score = formatValue(score.Score)
+	}
+	if request.Total > 0 {
+		row.SuccessRate = formatValue(float64(request.Successful) / float64(request.Total) * 100)
+	}
+	if stat := request.Statistics; stat != nil {
+		row.Mean = formatValue(stat.Mean)
+		row.Median = formatValue(stat.Median)
+	}
+	if stat := request.Statistics; stat != nil && stat.Percentiles["95.0"] > 0 {
+		row.P95 = formatValue(stat.Percentiles["95.0"])
+	} else if p95, ok := proxyQuantile(proxy.metrics, 95); ok {
+		row.P95 = formatValue(fromMicros(p95, unit))
+	}
+	return row
+}
+
+// proxyQuantile returns a percentile of a proxy's successful request times in
+// microseconds, from its histogram or raw samples
+func proxyQuantile(m *ProxyMetrics, percentile float64) (float64, bool) {
+	if histogram := m.RequestMetrics.Histogram; histogram != nil && histogram.Count() > 0 {
+		return float64(histogram.ValueAtPercentile(percentile)), true
+	}
+	if len(m.RequestMetrics.Times) == 0 {
+		return 0, false
+	}
+	value, _ := stats.Percentile(toFloat64s(m.RequestMetrics.Times), percentile)
+	return value, true
+}
+
+// formatValue formats a number for display with up to two decimals
+func formatValue(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// svgChart accumulates SVG elements for one chart
+type svgChart struct {
+	body   strings.Builder
+	height int
+}
+
+// newSVGChart starts a chart of the given height
+func newSVGChart(height int) *svgChart {
+	return &svgChart{height: height}
+}
+
+// add appends a formatted SVG element
+func (c *svgChart) add(format string, args ...interface{}) {
+	fmt.Fprintf(&c.body, format, args...)
+	c.body.WriteString("\n")
+}
+
+// text appends an escaped text label
+func (c *svgChart) text(x, y float64, anchor, label string) {
+	c.add(`<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`, x, y, anchor, template.HTMLEscapeString(label))
+}
+
+// axis draws a horizontal value axis from 0 to max at y with tick labels
+func (c *svgChart) axis(y, max float64, left, width int) {
+	c.add(`<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="axis"/>`, left, y, left+width, y)
+	for i := 0; i <= 4; i++ {
+		x := float64(left) + float64(width)*float64(i)/4
+		c.add(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" class="axis"/>`, x, y, x, y+4)
+		c.text(x, y+16, "middle", formatValue(max*float64(i)/4))
+	}
+}
+
+// html closes the chart and returns it as inline SVG
+func (c *svgChart) html() template.HTML {
+	return template.HTML(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%">`+"\n%s</svg>",
+		chartWidth, c.height, c.body.String()))
+}
+
+// niceMax rounds a maximum up so axis ticks are readable
+func niceMax(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, step := range []float64{1, 2, 2.5, 5, 10} {
+		if step*magnitude >= v {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// percentileChart draws grouped horizontal bars of every reported percentile per proxy
+func percentileChart(proxies []*htmlProxy) template.HTML {
+	keys := make(map[string]bool)
+	var max float64
+	for _, proxy := range proxies {
+		if stat := proxy.metrics.RequestMetrics.Statistics; stat != nil {
+			for key, value := range stat.Percentiles {
+				keys[key] = true
+				max = math.Max(max, value)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+
+	percentiles := make([]string, 0, len(keys))
+	for key := range keys {
+		percentiles = append(percentiles, key)
+	}
+	sort.Slice(percentiles, func(i, j int) bool {
+		pi, _ := strconv.ParseFloat(percentiles[i], 64)
+		pj, _ := strconv.ParseFloat(percentiles[j], 64)
+		return pi < pj
+	})
+
+	const barHeight = 14
+	groupHeight := len(proxies)*barHeight + 12
+	plotWidth := chartWidth - chartMarginLeft - chartMargin
+	max = niceMax(max)
+	chart := newSVGChart(len(percentiles)*groupHeight + 2*chartMargin)
+
+	for i, key := range percentiles {
+		top := float64(chartMargin + i*groupHeight)
+		chart.text(chartMarginLeft-10, top+float64(len(proxies)*barHeight)/2+4, "end", "p"+strings.TrimSuffix(key, ".0"))
+		for j, proxy := range proxies {
+			stat := proxy.metrics.RequestMetrics.Statistics
+			if stat == nil {
+				continue
+			}
+			value := stat.Percentiles[key]
+			y := top + float64(j*barHeight)
+			chart.add(`<rect x="%d" y="%.1f" width="%.1f" height="%d" fill="%s"><title>%s p%s: %s</title></rect>`,
+				chartMarginLeft, y, value/max*float64(plotWidth), barHeight-2, proxy.color,
+				template.HTMLEscapeString(proxy.id), key, formatValue(value))
+		}
+	}
+	chart.axis(float64(chartMargin+len(percentiles)*groupHeight), max, chartMarginLeft, plotWidth)
+	return chart.html()
+}
+
+// boxPlotChart draws a horizontal box plot (min, quartiles, median, max) per proxy
+func boxPlotChart(proxies []*htmlProxy, unit string) template.HTML {
+	type box struct {
+		proxy                    *htmlProxy
+		min, q1, median, q3, max float64
+	}
+
+	var boxes []box
+	var max float64
+	for _, proxy := range proxies {
+		stat := proxy.metrics.RequestMetrics.Statistics
+		q1, ok := proxyQuantile(proxy.metrics, 25)
+		if stat == nil || !ok {
+			continue
+		}
+		median, _ := proxyQuantile(proxy.metrics, 50)
+		q3, _ := proxyQuantile(proxy.metrics, 75)
+		boxes = append(boxes, box{
+			proxy:  proxy,
+			min:    stat.Min,
+			q1:     fromMicros(q1, unit),
+			median: fromMicros(median, unit),
+			q3:     fromMicros(q3, unit),
+			max:    stat.Max,
+		})
+		max = math.Max(max, stat.Max)
+	}
+	if len(boxes) == 0 {
+		return ""
+	}
+
+	const rowHeight = 32
+	plotWidth := chartWidth - chartMarginLeft - chartMargin
+	max = niceMax(max)
+	scale := func(v float64) float64 { return float64(chartMarginLeft) + v/max*float64(plotWidth) }
+	chart := newSVGChart(len(boxes)*rowHeight + 2*chartMargin)
+
+	for i, b := range boxes {
+		mid := float64(chartMargin + i*rowHeight + rowHeight/2)
+		chart.text(chartMarginLeft-10, mid+4, "end", b.proxy.id)
+		chart.add(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`, scale(b.min), mid, scale(b.max), mid, b.proxy.color)
+		chart.add(`<rect x="%.1f" y="%.1f" width="%.1f" height="%d" fill="%s" fill-opacity="0.6" stroke="%s"><title>min %s, q1 %s, median %s, q3 %s, max %s</title></rect>`,
+			scale(b.q1), mid-10, math.Max(scale(b.q3)-scale(b.q1), 1), 20, b.proxy.color, b.proxy.color,
+			formatValue(b.min), formatValue(b.q1), formatValue(b.median), formatValue(b.q3), formatValue(b.max))
+		chart.add(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#222" stroke-width="2"/>`, scale(b.median), mid-10, scale(b.median), mid+10)
+	}
+	chart.axis(float64(chartMargin+len(boxes)*rowHeight), max, chartMarginLeft, plotWidth)
+	return chart.html()
+}
+
+// timeSeriesChart draws the median latency of every time bucket as one line per proxy
+func timeSeriesChart(proxies []*htmlProxy) template.HTML {
+	var buckets int
+	var max float64
+	for _, proxy := range proxies {
+		buckets = int(math.Max(float64(buckets), float64(len(proxy.metrics.TimeSeries))))
+		for _, bucket := range proxy.metrics.TimeSeries {
+			if bucket.Statistics != nil {
+				max = math.Max(max, bucket.Statistics.Median)
+			}
+		}
+	}
+	if buckets == 0 || max == 0 {
+		return ""
+	}
+
+	const plotHeight = 220
+	left := chartMargin + 40
+	plotWidth := chartWidth - left - chartMargin
+	max = niceMax(max)
+	chart := newSVGChart(plotHeight + 2*chartMargin + 20*len(proxies))
+	step := float64(plotWidth)
+	if buckets > 1 {
+		step = float64(plotWidth) / float64(buckets-1)
+	}
+
+	chart.add(`<line x1="%d" y1="%d" x2="%d" y2="%d" class="axis"/>`, left, chartMargin, left, chartMargin+plotHeight)
+	chart.add(`<line x1="%d" y1="%d" x2="%d" y2="%d" class="axis"/>`, left, chartMargin+plotHeight, left+plotWidth, chartMargin+plotHeight)
+	for i := 0; i <= 4; i++ {
+		y := float64(chartMargin+plotHeight) - float64(plotHeight)*float64(i)/4
+		chart.text(float64(left-6), y+4, "end", formatValue(max*float64(i)/4))
+	}
+	chart.text(float64(left), float64(chartMargin+plotHeight+16), "start", "start")
+	chart.text(float64(left+plotWidth), float64(chartMargin+plotHeight+16), "end", fmt.Sprintf("bucket %d", buckets))
+
+	for i, proxy := range proxies {
+		var points []string
+		for j, bucket := range proxy.metrics.TimeSeries {
+			if bucket.Statistics == nil {
+				continue
+			}
+			x := float64(left) + float64(j)*step
+			y := float64(chartMargin+plotHeight) - bucket.Statistics.Median/max*plotHeight
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+			chart.add(`<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s %s: median %s, %d requests, %s%% errors</title></circle>`,
+				x, y, proxy.color, template.HTMLEscapeString(proxy.id), bucket.Start.Format("15:04:05"),
+				formatValue(bucket.Statistics.Median), bucket.Count, formatValue(bucket.ErrorRate*100))
+		}
+		if len(points) > 1 {
+			chart.add(`<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(points, " "), proxy.color)
+		}
+		legendY := float64(chartMargin + plotHeight + 36 + i*20)
+		chart.add(`<rect x="%d" y="%.1f" width="12" height="12" fill="%s"/>`, left, legendY-10, proxy.color)
+		chart.text(float64(left+18), legendY, "start", proxy.id)
+	}
+	return chart.html()
+}
+
+// histogramChart draws a proxy's request latency distribution in equal-width bins
+func histogramChart(proxy *htmlProxy, unit string) template.HTML {
+	var values []HistogramBucket
+	if histogram := proxy.metrics.RequestMetrics.Histogram; histogram != nil && histogram.Count() > 0 {
+		values = histogram.Buckets()
+	} else {
+		for _, v := range proxy.metrics.RequestMetrics.Times {
+			values = append(values, HistogramBucket{Value: v, Count: 1})
+		}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+
+	low, high := values[0].Value, values[0].Value
+	for _, v := range values {
+		low = min(low, v.Value)
+		high = max(high, v.Value)
+	}
+	width := float64(high-low) / histogramBins
+	if width == 0 {
+		width = 1
+	}
+	bins := make([]int64, histogramBins)
+	var tallest int64
+	for _, v := range values {
+		index := int(float64(v.Value-low) / width)
+		if index >= histogramBins {
+			index = histogramBins - 1
+		}
+		bins[index] += v.Count
+		tallest = max(tallest, bins[index])
+	}
+
+	const plotHeight = 140
+	left := chartMargin + 40
+	plotWidth := chartWidth - left - chartMargin
+	barWidth := float64(plotWidth) / histogramBins
+	chart := newSVGChart(plotHeight + 2*chartMargin)
+
+	for i, count := range bins {
+		height := float64(count) / float64(tallest) * plotHeight
+		from := fromMicros(float64(low)+float64(i)*width, unit)
+		to := fromMicros(float64(low)+float64(i+1)*width, unit)
+		chart.add(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s-%s: %d</title></rect>`,
+			float64(left)+float64(i)*barWidth+1, float64(chartMargin+plotHeight)-height, barWidth-2, height, proxy.color,
+			formatValue(from), formatValue(to), count)
+	}
+	chart.add(`<line x1="%d" y1="%d" x2="%d" y2="%d" class="axis"/>`, left, chartMargin+plotHeight, left+plotWidth, chartMargin+plotHeight)
+	chart.text(float64(left-6), float64(chartMargin+10), "end", strconv.FormatInt(tallest, 10))
+	chart.text(float64(left), float64(chartMargin+plotHeight+16), "start", formatValue(fromMicros(float64(low), unit)))
+	chart.text(float64(left+plotWidth), float64(chartMargin+plotHeight+16), "end", formatValue(fromMicros(float64(high), unit)))
+	return chart.html()
+}
+
+// htmlTemplate is the report page. It has no external assets so the file can
+// be opened offline or attached to a ticket.
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Proxy Benchmark Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 1000px; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { padding: 6px 10px; border-bottom: 1px solid #ddd; text-align: right; }
+th { cursor: pointer; background: #f5f5f5; user-select: none; }
+th:nth-child(2), td:nth-child(2) { text-align: left; }
+.swatch { display: inline-block; width: 10px; height: 10px; margin-right: 6px; }
+section { margin-bottom: 2em; }
+svg text { font-size: 11px; fill: #444; }
+svg .axis { stroke: #999; }
+</style>
+</head>
+<body>
+<h1>Proxy Benchmark Report</h1>
+<p>Generated {{.Generated}}. Latencies in {{.Unit}}. Click a column header to sort.</p>
+<table id="summary">
+<thead>
+<tr><th>Rank</th><th>Proxy</th><th>Score</th><th>Requests</th><th>Success %</th><th>Mean</th><th>Median</th><th>P95</th><th>Jitter</th></tr>
+</thead>
+<tbody>
+{{range .Rows}}<tr><td>{{.Rank}}</td><td><span class="swatch" style="background: {{.Color}}"></span>{{.Proxy}}</td><td>{{.Score}}</td><td>{{.Requests}}</td><td>{{.SuccessRate}}</td><td>{{.Mean}}</td><td>{{.Median}}</td><td>{{.P95}}</td><td>{{.Jitter}}</td></tr>
+{{end}}</tbody>
+</table>
+{{range .Charts}}<section>
+<h2>{{.Title}}</h2>
+{{.SVG}}
+</section>
+{{end}}<script>
+document.querySelectorAll("#summary th").forEach(function (th, column) {
+  th.addEventListener("click", function () {
+    var body = document.querySelector("#summary tbody");
+    var rows = Array.prototype.slice.call(body.rows);
+    var ascending = th.dataset.order !== "asc";
+    th.dataset.order = ascending ? "asc" : "desc";
+    rows.sort(function (a, b) {
+      var x = a.cells[column].textContent, y = b.cells[column].textContent;
+      var nx = parseFloat(x), ny = parseFloat(y);
+      var cmp = isNaN(nx) || isNaN(ny) ? x.localeCompare(y) : nx - ny;
+      return ascending ? cmp : -cmp;
+    });
+    rows.forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateHTML(t *testing.T) {
+	config := &Config{
+		Statistics: StatisticsConfig{Mean: true, Median: true, Percentiles: []float64{50, 95}},
+		Report: ReportConfig{
+			Unit:    "ms",
+			Scoring: &ScoringConfig{Weights: &ScoringWeights{SuccessRate: 1}},
+		},
+	}
+	reporter := NewReporter(config)
+
+	metrics := make(map[string]*Metrics)
+	for _, proxyString := range []string{"http:a.example:8080:user:secret:enabled", "socks:<b>:1080:::enabled"} {
+		m := NewMetrics(proxyString)
+		m.EnableTimeSeries(time.Now().Add(-time.Minute), 30*time.Second, 3)
+		for i := 1; i <= 20; i++ {
+			m.AddRequestTime(time.Duration(i*10)*time.Millisecond, true)
+		}
+		UpdateMetricsStatistics(m, &config.Statistics)
+		metrics[proxyString] = m
+	}
+
+	page, err := reporter.GenerateHTML(reporter.GenerateReport(metrics))
+	if err != nil {
+		t.Fatalf("failed to render HTML: %v", err)
+	}
+
+	for _, want := range []string{"<table id=\"summary\">", "http://a.example:8080", "percentiles", "distribution", "over time", "histogram", "<svg"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("expected report to contain %q", want)
+		}
+	}
+	if strings.Contains(page, "secret") {
+		t.Error("expected credentials to be left out of the report")
+	}
+	if strings.Contains(page, "<b>") {
+		t.Error("expected proxy names to be escaped")
+	}
+	for _, external := range []string{"src=\"http", "href=\"http", "<link"} {
+		if strings.Contains(page, external) {
+			t.Errorf("expected no external assets, found %q", external)
+		}
+	}
+}
+
+func TestNiceMax(t *testing.T) {
+	cases := map[float64]float64{0: 1, 7: 10, 180: 200, 230: 250, 1000: 1000}
+	for input, want := range cases {
+		if got := niceMax(input); got != want {
+			t.Errorf("niceMax(%v) = %v, want %v", input, got, want)
+		}
+	}
+}
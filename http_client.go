@@ -50,6 +50,10 @@ func (h *HTTPClient) MakeRequest(ctx context.Context, targetURL string) ([]byte,
 	}
 	defer resp.Body.Close()
 
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -57,3 +61,21 @@ func (h *HTTPClient) MakeRequest(ctx context.Context, targetURL string) ([]byte,
 
 	return body, nil
 }
+
+// HTTPStatusError reports a response with a non-2xx status code
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected HTTP status %s", e.Status)
+}
+
+// checkStatus returns an HTTPStatusError unless resp has a 2xx status code
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &HTTPStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Influx export modes
+const (
+	InfluxPerSample = "per_sample"
+	InfluxPerBucket = "per_bucket"
+)
+
+// influxBatchSize is the number of lines sent per write request
+const influxBatchSize = 5000
+
+// InfluxExporter renders benchmark results as InfluxDB line protocol. Every
+// point is tagged with the proxy ID, its group, the target and the run ID.
+type InfluxExporter struct {
+	config *InfluxConfig
+	target string
+	runID  string
+	client *http.Client
+}
+
+// NewInfluxExporter creates an exporter for the results of one run
+func NewInfluxExporter(config *InfluxConfig, target, runID string, timeout time.Duration) *InfluxExporter {
+	return &InfluxExporter{
+		config: config,
+		target: target,
+		runID:  runID,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// validateInfluxConfig checks that the points go somewhere and that the
+// statistics configuration records what the mode exports
+func validateInfluxConfig(influx *InfluxConfig, statistics *StatisticsConfig) error {
+	if influx.File == "" && influx.URL == "" {
+		return fmt.Errorf("file or url must be set")
+	}
+	switch influx.Mode {
+	case InfluxPerSample:
+		if histogram := statistics.Histogram; histogram != nil && histogram.Enabled && !histogram.KeepSamples {
+			return fmt.Errorf("mode %s requires histogram.keep_samples when histograms are enabled", InfluxPerSample)
+		}
+	case InfluxPerBucket:
+		if statistics.TimeSeries == nil || !statistics.TimeSeries.Enabled {
+			return fmt.Errorf("mode %s requires statistics.time_series to be enabled", InfluxPerBucket)
+		}
+	default:
+		return fmt.Errorf("unsupported mode %q (expected %s or %s)", influx.Mode, InfluxPerSample, InfluxPerBucket)
+	}
+	return nil
+}
+
+// Lines returns the line protocol points of a result in the configured mode
+func (e *InfluxExporter) Lines(result *BenchmarkResult) []string {
+	var lines []string
+	for _, proxy := range sortedProxies(result) {
+		tags := e.tags(proxy.ProxyString)
+		if e.config.Mode == InfluxPerBucket {
+			lines = append(lines, e.bucketLines(tags, proxy, result.Unit)...)
+		} else {
+			lines = append(lines, e.sampleLines(tags, proxy, result.Timestamp)...)
+		}
+	}
+	return lines
+}
+
+// sampleLines writes one point per raw sample at its completion time. Results
+// recorded without sample timestamps place each sample at the result timestamp
+// offset by its index in nanoseconds to keep the points of a series distinct.
+// Derived times are computed from the requests and share their timestamps.
+func (e *InfluxExporter) sampleLines(tags string, proxy *ProxyMetrics, timestamp time.Time) []string {
+	series := []struct {
+		metric     string
+		values     []int64
+		timestamps []int64
+	}{
+		{"request", proxy.RequestMetrics.Times, proxy.RequestMetrics.Timestamps},
+		{"failed_request", proxy.RequestMetrics.FailedTimes, proxy.RequestMetrics.FailedTimestamps},
+		{"ping", proxy.PingMetrics.Times, proxy.PingMetrics.Timestamps},
+		{"derived", proxy.DerivedMetrics.ProcessingTimes, proxy.RequestMetrics.Timestamps},
+	}
+
+	var lines []string
+	for _, s := range series {
+		prefix := e.measurement() + tags + ",metric=" + s.metric
+		for i, value := range s.values {
+			at := timestamp.UnixNano() + int64(i)
+			if i < len(s.timestamps) {
+				at = s.timestamps[i]
+			}
+			lines = append(lines, fmt.Sprintf("%s value_us=%di,index=%di %d", prefix, value, i, at))
+		}
+	}
+	return lines
+}
+
+// bucketLines writes one point per time series bucket at the bucket's start
+func (e *InfluxExporter) bucketLines(tags string, proxy *ProxyMetrics, unit string) []string {
+	lines := make([]string, 0, len(proxy.TimeSeries))
+	for _, bucket := range proxy.TimeSeries {
+		fields := []string{
+			"count=" + strconv.Itoa(bucket.Count) + "i",
+			"successful=" + strconv.Itoa(bucket.Successful) + "i",
+			"failed=" + strconv.Itoa(bucket.Failed) + "i",
+			"error_rate=" + formatFloat(bucket.ErrorRate),
+		}
+		if stat := bucket.Statistics; stat != nil {
+			micros := func(v float64) string { return formatFloat(toMicros(v, unit)) }
+			fields = append(fields,
+				"min_us="+micros(stat.Min), "max_us="+micros(stat.Max),
+				"mean_us="+micros(stat.Mean), "median_us="+micros(stat.Median))
+			keys := make([]string, 0, len(stat.Percentiles))
+			for key := range stat.Percentiles {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fields = append(fields, "p"+strings.TrimSuffix(key, ".0")+"_us="+micros(stat.Percentiles[key]))
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s%s,metric=request %s %d",
+			e.measurement(), tags, strings.Join(fields, ","), bucket.Start.UnixNano()))
+	}
+	return lines
+}
+
+// measurement returns the escaped measurement name
+func (e *InfluxExporter) measurement() string {
+	return influxMeasurementEscaper.Replace(e.config.Measurement)
+}
+
+// tags returns the escaped tag set shared by all points of a proxy. Tags without
+// a value are omitted as line protocol does not allow empty tag values.
+func (e *InfluxExporter) tags(proxyString string) string {
+	var b strings.Builder
+	for _, tag := range [][2]string{
+		{"proxy", proxyID(proxyString)},
+		{"group", proxyGroup(proxyString, e.config.Groups)},
+		{"target", redactURL(e.target)},
+		{"run_id", e.runID},
+	} {
+		if tag[1] == "" {
+			continue
+		}
+		b.WriteString("," + tag[0] + "=" + influxTagEscaper.Replace(tag[1]))
+	}
+	return b.String()
+}
+
+// proxyGroup looks up a proxy's group by ID, then by host:port
+func proxyGroup(proxyString string, groups map[string]string) string {
+	proxy, err := ParseProxy(proxyString)
+	if err != nil {
+		return groups[proxyString]
+	}
+	if group, ok := groups[proxy.ID()]; ok {
+		return group
+	}
+	return groups[proxy.Address()]
+}
+
+// influxMeasurementEscaper escapes commas and spaces in measurement names
+var influxMeasurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+
+// influxTagEscaper escapes commas, equals signs and spaces in tag values
+var influxTagEscaper = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+
+// WriteFile writes the points of a result to a line protocol file
+func (e *InfluxExporter) WriteFile(result *BenchmarkResult, path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, line := range e.Lines(result) {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Post sends the points of a result to the configured write endpoint in batches
+func (e *InfluxExporter) Post(result *BenchmarkResult) error {
+	lines := e.Lines(result)
+	for start := 0; start < len(lines); start += influxBatchSize {
+		end := start + influxBatchSize
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if err := e.post(strings.Join(lines[start:end], "\n") + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// post sends one batch of lines
+func (e *InfluxExporter) post(body string) error {
+	req, err := http.NewRequest(http.MethodPost, e.config.URL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if e.config.Token != "" {
+		req.Header.Set("Authorization", "Token "+e.config.Token)
+	}
+	for name, value := range e.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("write endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
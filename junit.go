@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups the test cases of one proxy
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a single check; Failure is set if it did not pass
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+// junitFailure describes why a test case failed
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// GenerateJUnit renders a benchmark result as JUnit test suites: one suite per
+// proxy holding the proxy itself, each SLO, each response validation check and
+// each threshold rule that applies to it
+func (r *Reporter) GenerateJUnit(result *BenchmarkResult) *junitTestSuites {
+	suites := &junitTestSuites{Name: "proxy-benchmark"}
+
+	proxies := make([]*ProxyMetrics, len(result.Proxies))
+	copy(proxies, result.Proxies)
+	sort.Slice(proxies, func(i, j int) bool { return proxies[i].ProxyString < proxies[j].ProxyString })
+
+	for _, proxy := range proxies {
+		suite := junitTestSuite{
+			Name:      proxyID(proxy.ProxyString),
+			Timestamp: result.Timestamp.Format("2006-01-02T15:04:05"),
+		}
+		suite.Cases = append(suite.Cases, r.junitProxyCase(proxy))
+		for _, slo := range proxy.SLOs {
+			suite.Cases = append(suite.Cases, junitSLOCase(suite.Name, slo))
+		}
+		for _, check := range proxy.Validation {
+			suite.Cases = append(suite.Cases, junitValidationCase(suite.Name, check))
+		}
+		if result.Gate != nil {
+			for _, rule := range result.Gate.Rules {
+				if testCase := junitThresholdCase(suite.Name, rule); testCase != nil {
+					suite.Cases = append(suite.Cases, *testCase)
+				}
+			}
+		}
+
+		for _, testCase := range suite.Cases {
+			suite.Tests++
+			suite.Time += testCase.Time
+			if testCase.Failure != nil {
+				suite.Failures++
+			}
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Time += suite.Time
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	return suites
+}
+
+// junitProxyCase fails if the proxy served no successful request. Its time is
+// the total time spent in successful requests.
+func (r *Reporter) junitProxyCase(proxy *ProxyMetrics) junitTestCase {
+	id := proxyID(proxy.ProxyString)
+	request := proxy.RequestMetrics
+	testCase := junitTestCase{Name: "requests", ClassName: id, Time: request.SuccessfulTime() / 1e6}
+
+	if request.Successful == 0 {
+		testCase.Failure = &junitFailure{
+			Message: fmt.Sprintf("no successful requests out of %d", request.Total),
+			Type:    "unavailable",
+			Text:    describeFailures(request),
+		}
+	}
+	return testCase
+}
+
+// describeFailures lists the failed requests per error class
+func describeFailures(request RequestMetrics) string {
+	classes := make([]string, 0, len(request.FailuresByClass))
+	for class := range request.FailuresByClass {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	lines := make([]string, 0, len(classes))
+	for _, class := range classes {
+		lines = append(lines, fmt.Sprintf("%s: %d", class, request.FailuresByClass[class].Count))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// junitSLOCase reports an SLO with every missed check in the failure text
+func junitSLOCase(id string, slo *SLOResult) junitTestCase {
+	testCase := junitTestCase{Name: "slo/" + slo.Name, ClassName: id}
+	if slo.Passed {
+		return testCase
+	}
+
+	var missed []string
+	for _, check := range slo.Checks {
+		if check.Passed {
+			continue
+		}
+		name := check.Check
+		if check.Class != "" {
+			name += " " + check.Class
+		}
+		missed = append(missed, fmt.Sprintf("%s: target %g, actual %g, margin %g", name, check.Target, check.Actual, check.Margin))
+	}
+	testCase.Failure = &junitFailure{
+		Message: fmt.Sprintf("SLO %s not met", slo.Name),
+		Type:    "slo",
+		Text:    strings.Join(missed, "\n"),
+	}
+	return testCase
+}
+
+// junitValidationCase fails if a response validation check failed at least once
+func junitValidationCase(id string, check *ValidationCheckResult) junitTestCase {
+	testCase := junitTestCase{Name: fmt.Sprintf("validation/%s (%s)", check.Path, check.Type), ClassName: id}
+	if check.Failed > 0 {
+		testCase.Failure = &junitFailure{
+			Message: fmt.Sprintf("failed %d of %d responses", check.Failed, check.Passed+check.Failed),
+			Type:    "validation",
+			Text:    check.LastError,
+		}
+	}
+	return testCase
+}
+
+// junitThresholdCase reports a threshold rule for a proxy it matched, or
+// returns nil if the rule does not apply to the proxy. A proxy that missed a
+// min_proxies rule only fails if too few other proxies met it.
+func junitThresholdCase(id string, rule *GateRuleResult) *junitTestCase {
+	if !containsString(rule.Proxies, id) {
+		return nil
+	}
+
+	testCase := &junitTestCase{Name: "threshold/" + rule.Name, ClassName: id}
+	if !rule.Passed && containsString(rule.Failed, id) {
+		testCase.Failure = &junitFailure{
+			Message: fmt.Sprintf("threshold %s not met", rule.Name),
+			Type:    "threshold",
+			Text:    fmt.Sprintf("%d of %d matched proxies met the rule, %d required", rule.Met, rule.Matched, rule.Required),
+		}
+	}
+	return testCase
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveJUnit saves the benchmark result as a JUnit XML report
+func (r *Reporter) SaveJUnit(result *BenchmarkResult, filepath string) error {
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(file)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(r.GenerateJUnit(result)); err != nil {
+		return err
+	}
+	_, err = file.WriteString("\n")
+	return err
+}
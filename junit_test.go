@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateJUnit(t *testing.T) {
+	config := &Config{
+		Statistics: StatisticsConfig{Median: true},
+		Report: ReportConfig{
+			Unit: "ms",
+			SLOs: []SLOConfig{{Name: "fast", Percentile: 50, LatencyMs: 200}},
+		},
+		Thresholds: []ThresholdRule{{Name: "reliable", MinSuccessRate: 90}},
+	}
+	reporter := NewReporter(config)
+
+	good := NewMetrics("http:good:8080:user:pass:enabled")
+	good.AddRequestTime(100*time.Millisecond, true)
+	good.AddRequestTime(100*time.Millisecond, true)
+	checks := []ValidationCheck{{Path: "url", Type: "string"}}
+	good.AddValidationResults(checks, []error{nil})
+	good.AddValidationResults(checks, []error{errors.New("validation failed for path 'url'")})
+
+	down := NewMetrics("socks:down:1080:::enabled")
+	down.AddRequestFailure(time.Second, context.DeadlineExceeded)
+
+	metrics := map[string]*Metrics{good.ProxyString: good, down.ProxyString: down}
+	for _, m := range metrics {
+		UpdateMetricsStatistics(m, &config.Statistics)
+	}
+	suites := reporter.GenerateJUnit(reporter.GenerateReport(metrics))
+
+	if len(suites.Suites) != 2 || suites.Suites[0].Name != "http://good:8080" {
+		t.Fatalf("expected suites per proxy ID sorted by proxy, got %+v", suites.Suites)
+	}
+	goodSuite := suites.Suites[0]
+	if goodSuite.Tests != 4 || goodSuite.Failures != 1 {
+		t.Errorf("expected 4 cases with only the validation check failing, got %d / %d", goodSuite.Tests, goodSuite.Failures)
+	}
+	if goodSuite.Cases[0].Time != 0.2 {
+		t.Errorf("expected proxy case time 0.2s, got %v", goodSuite.Cases[0].Time)
+	}
+
+	downSuite := suites.Suites[1]
+	if downSuite.Failures != 3 {
+		t.Errorf("expected requests, SLO and threshold to fail for the down proxy, got %d failures", downSuite.Failures)
+	}
+	if failure := downSuite.Cases[0].Failure; failure == nil || !strings.Contains(failure.Text, "timeout: 1") {
+		t.Errorf("expected failure listing the timeout, got %+v", failure)
+	}
+	if suites.Tests != 7 || suites.Failures != 4 {
+		t.Errorf("expected 7 tests / 4 failures overall, got %d / %d", suites.Tests, suites.Failures)
+	}
+}
+
+func TestSaveJUnit(t *testing.T) {
+	reporter := NewReporter(&Config{Report: ReportConfig{Unit: "ms"}})
+	metrics := NewMetrics("http:proxy:8080:secretuser:secretpass:enabled")
+	metrics.AddRequestTime(50*time.Millisecond, true)
+	result := reporter.GenerateReport(map[string]*Metrics{metrics.ProxyString: metrics})
+
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	if err := reporter.SaveJUnit(result, path); err != nil {
+		t.Fatalf("failed to save JUnit report: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Error("expected credentials to be left out of the report")
+	}
+
+	var parsed junitTestSuites
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+	if parsed.Tests != 1 || parsed.Suites[0].Cases[0].Name != "requests" {
+		t.Errorf("unexpected report: %+v", parsed)
+	}
+}
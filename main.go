@@ -4,12 +4,30 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 )
 
 func main() {
+	// Subcommands have their own flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "compare":
+			os.Exit(runCompare(os.Args[2:]))
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		}
+	}
+
 	// Parse command line flags
 	configPath := flag.String("config", "config.json", "Path to the configuration file")
+	unit := flag.String("unit", "", "Display unit for reported statistics (ns, us, ms, s)")
+	format := flag.String("format", "", "Comma-separated reports to write (json, short, junit, html, csv, samples_csv, markdown)")
+	outputDir := flag.String("output-dir", "", "Directory to write reports to; may use {run_id}, {timestamp} and {date}")
+	nameTemplate := flag.String("name-template", "", "Report file name template, e.g. {timestamp}_{name}")
+	runID := flag.String("run-id", "", "Identifier of this run (generated if empty)")
 	flag.Parse()
 
 	// Check if config file exists
@@ -24,6 +42,26 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
+	// Command line flags override the configuration file
+	if *unit != "" {
+		config.Report.Unit = *unit
+	}
+	if *outputDir != "" {
+		config.Output.Dir = *outputDir
+	}
+	if *nameTemplate != "" {
+		config.Output.NameTemplate = *nameTemplate
+	}
+	if *runID != "" {
+		config.Output.RunID = *runID
+	}
+	if *format != "" {
+		config.Report.Outputs = strings.Split(*format, ",")
+		for i, output := range config.Report.Outputs {
+			config.Report.Outputs[i] = strings.TrimSpace(output)
+		}
+	}
+
 	// Set default values if not specified
 	if config.Benchmark.Requests == 0 {
 		config.Benchmark.Requests = 100
@@ -43,6 +81,224 @@ func main() {
 	if config.Benchmark.TimeoutMs == 0 {
 		config.Benchmark.TimeoutMs = 30000
 	}
+	if config.Output.NameTemplate == "" {
+		config.Output.NameTemplate = "{name}"
+	}
+	if err := validateOutputConfig(&config.Output); err != nil {
+		log.Fatalf("Invalid output configuration: %v", err)
+	}
+	if config.Output.RunID == "" {
+		config.Output.RunID = newRunID(time.Now())
+	}
+	fmt.Printf("Run ID: %s\n", config.Output.RunID)
+	if len(config.Report.Outputs) == 0 {
+		config.Report.Outputs = []string{"json", "short"}
+	}
+	if err := validateOutputs(config.Report.Outputs); err != nil {
+		log.Fatalf("Invalid report configuration: %v", err)
+	}
+	if config.Report.Unit == "" {
+		config.Report.Unit = "ms"
+	}
+	if err := validateDisplayUnit(config.Report.Unit); err != nil {
+		log.Fatalf("Invalid report configuration: %v", err)
+	}
+	if significance := config.Report.Significance; significance != nil && significance.Alpha == 0 {
+		significance.Alpha = 0.05
+	}
+	if config.Report.Scoring == nil {
+		config.Report.Scoring = &ScoringConfig{}
+	}
+	if config.Report.Scoring.Weights == nil {
+		config.Report.Scoring.Weights = &ScoringWeights{
+			SuccessRate: 0.4,
+			P50:         0.2,
+			P95:         0.2,
+			Jitter:      0.1,
+			Throughput:  0.1,
+		}
+	}
+	if err := validateScoringWeights(config.Report.Scoring.Weights); err != nil {
+		log.Fatalf("Invalid scoring configuration: %v", err)
+	}
+	if err := validateThresholds(config.Thresholds); err != nil {
+		log.Fatalf("Invalid threshold configuration: %v", err)
+	}
+	if err := validateSLOs(config.Report.SLOs); err != nil {
+		log.Fatalf("Invalid SLO configuration: %v", err)
+	}
+	if apdex := config.Report.Apdex; apdex != nil {
+		if apdex.SatisfiedMs <= 0 {
+			log.Fatalf("Invalid Apdex configuration: satisfied_ms must be positive")
+		}
+		if apdex.ToleratingMs == 0 {
+			apdex.ToleratingMs = 4 * apdex.SatisfiedMs
+		}
+		if apdex.ToleratingMs < apdex.SatisfiedMs {
+			log.Fatalf("Invalid Apdex configuration: tolerating_ms must not be below satisfied_ms")
+		}
+	}
+	if ping := config.Benchmark.Ping; ping != nil && ping.Mode != "" && ping.Mode != "tcp" && ping.Mode != "handshake" {
+		log.Fatalf("Invalid ping mode %q: expected \"tcp\" or \"handshake\"", ping.Mode)
+	}
+	if config.Benchmark.WebSocket == nil && strings.HasPrefix(config.Benchmark.TargetURL, "ws") {
+		config.Benchmark.WebSocket = &WebSocketConfig{}
+	}
+	if ws := config.Benchmark.WebSocket; ws != nil {
+		if ws.Payload == "" {
+			ws.Payload = "ping"
+		}
+		if ws.Messages == 0 {
+			ws.Messages = 10
+		}
+		if ws.MessageIntervalMs == 0 {
+			ws.MessageIntervalMs = 1000
+		}
+	}
+	if bootstrap := config.Statistics.Bootstrap; bootstrap != nil {
+		if bootstrap.Level == 0 {
+			bootstrap.Level = 0.95
+		}
+		if bootstrap.Resamples == 0 {
+			bootstrap.Resamples = 1000
+		}
+		if bootstrap.Seed == nil {
+			seed := time.Now().UnixNano()
+			bootstrap.Seed = &seed
+		}
+		if bootstrap.Level <= 0 || bootstrap.Level >= 1 {
+			log.Fatalf("Invalid bootstrap level %v: must be between 0 and 1", bootstrap.Level)
+		}
+		if bootstrap.Resamples < 0 {
+			log.Fatalf("Invalid bootstrap resamples %d: must be positive", bootstrap.Resamples)
+		}
+		if bootstrap.Enabled {
+			fmt.Printf("Bootstrap confidence intervals use seed %d\n", *bootstrap.Seed)
+		}
+	}
+	if histogram := config.Statistics.Histogram; histogram != nil && histogram.SignificantFigures == 0 {
+		histogram.SignificantFigures = 3
+	}
+	// Without kept samples only histograms remain, which cannot be resampled
+	histogram := config.Statistics.Histogram
+	samplesDropped := histogram != nil && histogram.Enabled && !histogram.KeepSamples
+	if bootstrap := config.Statistics.Bootstrap; bootstrap != nil && bootstrap.Enabled && samplesDropped {
+		log.Fatalf("Invalid bootstrap configuration: confidence intervals require histogram.keep_samples")
+	}
+	if significance := config.Report.Significance; significance != nil && significance.Enabled && samplesDropped {
+		log.Fatalf("Invalid significance configuration: significance tests require histogram.keep_samples")
+	}
+	if outliers := config.Statistics.Outliers; outliers != nil && outliers.Enabled && samplesDropped {
+		log.Fatalf("Invalid outlier configuration: robust statistics require histogram.keep_samples")
+	}
+	if outliers := config.Statistics.Outliers; outliers != nil {
+		if outliers.Method == "" {
+			outliers.Method = "iqr"
+		}
+		if outliers.Method != "iqr" && outliers.Method != "mad" {
+			log.Fatalf("Invalid outlier method %q: expected \"iqr\" or \"mad\"", outliers.Method)
+		}
+		if outliers.Threshold == 0 {
+			if outliers.Method == "mad" {
+				outliers.Threshold = 3.5
+			} else {
+				outliers.Threshold = 1.5
+			}
+		}
+		if outliers.Threshold < 0 {
+			log.Fatalf("Invalid outlier threshold %v: must be positive", outliers.Threshold)
+		}
+		if outliers.TrimPercent == 0 {
+			outliers.TrimPercent = 5
+		}
+		if outliers.TrimPercent < 0 || outliers.TrimPercent >= 50 {
+			log.Fatalf("Invalid outlier trim_percent %v: must be between 0 and 50", outliers.TrimPercent)
+		}
+	}
+	if timeSeries := config.Statistics.TimeSeries; timeSeries != nil {
+		if timeSeries.BucketWidthMs == 0 {
+			timeSeries.BucketWidthMs = 60000
+		}
+		if timeSeries.BucketWidthMs <= 0 {
+			log.Fatalf("Invalid time series bucket_width_ms %d: must be positive", timeSeries.BucketWidthMs)
+		}
+	}
+	if stability := config.Benchmark.Stability; stability != nil {
+		if stability.Tunnels == 0 {
+			stability.Tunnels = 5
+		}
+		if stability.DurationMs == 0 {
+			stability.DurationMs = 600000
+		}
+		if stability.HeartbeatIntervalMs == 0 {
+			stability.HeartbeatIntervalMs = 30000
+		}
+		if stability.Heartbeat == "" {
+			stability.Heartbeat = "\n"
+		}
+		// Tunnels default to the echo server of a tcp:// target URL
+		if target, err := url.Parse(config.Benchmark.TargetURL); err == nil && stability.Target == "" && strings.EqualFold(target.Scheme, "tcp") {
+			stability.Target = target.Host
+		}
+		if stability.Enabled && stability.Target == "" {
+			log.Fatalf("Invalid stability configuration: stability.target must be set to an echo server host:port")
+		}
+	}
+	if udp := config.Benchmark.UDP; udp != nil {
+		if udp.Enabled && udp.Target == "" {
+			log.Fatalf("Invalid UDP configuration: udp.target must be set to an echo server host:port")
+		}
+		if udp.Associations == 0 {
+			udp.Associations = 5
+		}
+		if udp.Datagrams == 0 {
+			udp.Datagrams = 20
+		}
+		if udp.IntervalMs == 0 {
+			udp.IntervalMs = 100
+		}
+		if udp.DatagramTimeoutMs == 0 {
+			udp.DatagramTimeoutMs = 1000
+		}
+		if udp.DatagramTimeoutMs < 0 {
+			log.Fatalf("Invalid UDP configuration: udp.datagram_timeout_ms must be positive")
+		}
+	}
+
+	if prometheus := config.Exporters.Prometheus; prometheus != nil {
+		if prometheus.Listen == "" && prometheus.Textfile == "" {
+			log.Fatalf("Invalid prometheus configuration: listen or textfile must be set")
+		}
+		if prometheus.Namespace == "" {
+			prometheus.Namespace = "proxy_benchmark"
+		}
+	}
+	if influx := config.Exporters.Influx; influx != nil {
+		if influx.Mode == "" {
+			influx.Mode = InfluxPerSample
+		}
+		if influx.Measurement == "" {
+			influx.Measurement = "proxy_benchmark"
+		}
+		if err := validateInfluxConfig(influx, &config.Statistics); err != nil {
+			log.Fatalf("Invalid influx configuration: %v", err)
+		}
+	}
+	var baseline *BenchmarkResult
+	if compare := config.Compare; compare != nil {
+		setCompareDefaults(compare)
+		if err := validateCompareConfig(compare); err != nil {
+			log.Fatalf("Invalid compare configuration: %v", err)
+		}
+		if compare.Baseline != "" {
+			if baseline, err = loadResult(compare.Baseline); err != nil {
+				log.Fatalf("Invalid compare configuration: failed to load baseline: %v", err)
+			}
+		}
+	}
+	if history := config.History; history != nil && history.Path == "" {
+		history.Path = "history.db"
+	}
 
 	// Create benchmark engine
 	fmt.Println("Initializing benchmark engine...")
@@ -51,31 +307,120 @@ func main() {
 		log.Fatalf("Failed to create benchmark engine: %v", err)
 	}
 
+	// Serve live metrics while the benchmark runs
+	var exporter *PrometheusExporter
+	if prometheus := config.Exporters.Prometheus; prometheus != nil {
+		exporter = NewPrometheusExporter(prometheus, engine.GetResults)
+		if prometheus.Listen != "" {
+			if _, err := exporter.Serve(prometheus.Listen); err != nil {
+				log.Fatalf("Failed to start metrics server: %v", err)
+			}
+			fmt.Printf("Serving Prometheus metrics on http://%s/metrics\n", prometheus.Listen)
+		}
+	}
+
 	// Run benchmark
+	started := time.Now()
 	if err := engine.Run(); err != nil {
 		log.Fatalf("Benchmark failed: %v", err)
 	}
+	finished := time.Now()
 
 	// Generate and save report
 	fmt.Println("Generating report...")
-	reporter := NewReporter()
+	reporter := NewReporter(config)
 	results := engine.GetResults()
 	report := reporter.GenerateReport(results)
+	report.Metadata = newRunMetadata(config, started, finished)
+
+	for _, output := range config.Report.Outputs {
+		path, err := config.Output.Path(output, started)
+		if err != nil {
+			log.Fatalf("Failed to prepare %s output: %v", output, err)
+		}
 
-	fmt.Println("Saving results to result.json...")
-	if err := reporter.SaveReport(report, "result.json"); err != nil {
-		log.Fatalf("Failed to save report: %v", err)
+		fmt.Printf("Saving %s output to %s...\n", output, path)
+		switch output {
+		case "json":
+			err = reporter.SaveReport(report, path)
+		case "short":
+			err = reporter.SaveShortSummary(reporter.GenerateShortSummary(results), path)
+		case "junit":
+			err = reporter.SaveJUnit(report, path)
+		case "html":
+			err = reporter.SaveHTML(report, path)
+		case "csv":
+			err = reporter.SaveCSV(report, path)
+		case "samples_csv":
+			err = reporter.SaveSamplesCSV(report, path)
+		case "markdown":
+			err = reporter.SaveMarkdown(report, path)
+		}
+		if err != nil {
+			log.Fatalf("Failed to save %s output: %v", output, err)
+		}
 	}
+	fmt.Printf("Reports for run %s saved\n", config.Output.RunID)
 
-	// Generate and save short summary
-	fmt.Println("Generating short summary...")
-	shortSummary := reporter.GenerateShortSummary(results)
+	if exporter != nil {
+		exporter.SetResult(report)
+		if textfile := config.Exporters.Prometheus.Textfile; textfile != "" {
+			fmt.Printf("Writing Prometheus metrics to %s...\n", textfile)
+			if err := exporter.WriteTextfile(textfile); err != nil {
+				fmt.Printf("Warning: failed to write Prometheus metrics: %v\n", err)
+			}
+		}
+	}
+
+	if influx := config.Exporters.Influx; influx != nil {
+		exporter := NewInfluxExporter(influx, config.Benchmark.TargetURL, config.Output.RunID,
+			time.Duration(config.Benchmark.TimeoutMs)*time.Millisecond)
+		if influx.File != "" {
+			path := expandTemplate(influx.File, "influx", config.Output.RunID, started)
+			fmt.Printf("Writing line protocol to %s...\n", path)
+			if err := exporter.WriteFile(report, path); err != nil {
+				fmt.Printf("Warning: failed to write line protocol: %v\n", err)
+			}
+		}
+		if influx.URL != "" {
+			fmt.Println("Sending line protocol to the write endpoint...")
+			if err := exporter.Post(report); err != nil {
+				fmt.Printf("Warning: failed to send line protocol: %v\n", err)
+			}
+		}
+	}
 
-	fmt.Println("Saving short summary to results_short.json...")
-	if err := reporter.SaveShortSummary(shortSummary, "results_short.json"); err != nil {
-		log.Fatalf("Failed to save short summary: %v", err)
+	if history := config.History; history != nil {
+		fmt.Printf("Appending run to history %s...\n", history.Path)
+		store, err := OpenHistory(history.Path, false)
+		if err != nil {
+			log.Fatalf("Failed to open history: %v", err)
+		}
+		err = store.Append(newRunRecord(report, config.Benchmark.TargetURL))
+		store.Close()
+		if err != nil {
+			log.Fatalf("Failed to append run to history: %v", err)
+		}
 	}
 
-	fmt.Println("Benchmark results saved to result.json")
-	fmt.Println("Short summary saved to results_short.json")
+	// Fail the run if any threshold rule was not met or it regressed against the baseline
+	failed := false
+	if report.Gate != nil && !report.Gate.Passed {
+		fmt.Println("Threshold check failed:")
+		for _, failure := range report.Gate.Failures {
+			fmt.Printf("  - %s\n", failure)
+		}
+		failed = true
+	}
+	if baseline != nil {
+		comparison := compareResults(baseline, report, config.Compare)
+		fmt.Println()
+		WriteComparison(os.Stdout, comparison)
+		if !comparison.Passed {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(2)
+	}
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenerateMarkdown renders a compact Markdown summary table of a benchmark
+// result, ordered by rank, for pasting into pull requests and issues
+func (r *Reporter) GenerateMarkdown(result *BenchmarkResult) string {
+	proxies := sortedProxies(result)
+	scores := make(map[string]*ProxyScore, len(result.Ranking))
+	for _, score := range result.Ranking {
+		scores[score.Proxy] = score
+	}
+	sort.SliceStable(proxies, func(i, j int) bool {
+		si, sj := scores[proxies[i].ProxyString], scores[proxies[j].ProxyString]
+		return si != nil && (sj == nil || si.Rank < sj.Rank)
+	})
+
+	percentiles := percentileKeys(proxies)
+
+	header := []string{"Rank", "Proxy", "Score", "Requests", "Success %", "Mean", "Median"}
+	for _, key := range percentiles {
+		header = append(header, "P"+strings.TrimSuffix(key, ".0"))
+	}
+	header = append(header, "Ping mean", "Jitter")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Proxy Benchmark Results\n\n")
+	fmt.Fprintf(&b, "Generated %s. Latencies in %s.\n\n", result.Timestamp.Format("2006-01-02 15:04:05 MST"), result.Unit)
+	b.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+
+	for _, proxy := range proxies {
+		request := proxy.RequestMetrics
+		rank, score, successRate := "-", "-", "-"
+		if s, ok := scores[proxy.ProxyString]; ok {
+			rank, score = strconv.Itoa(s.Rank), formatValue(s.Score)
+		}
+		if request.Total > 0 {
+			successRate = formatValue(float64(request.Successful) / float64(request.Total) * 100)
+		}
+
+		row := []string{rank, "`" + proxyID(proxy.ProxyString) + "`", score,
+			fmt.Sprintf("%d/%d", request.Successful, request.Total), successRate}
+		if stat := request.Statistics; stat != nil {
+			row = append(row, formatValue(stat.Mean), formatValue(stat.Median))
+			for _, key := range percentiles {
+				if value, ok := stat.Percentiles[key]; ok {
+					row = append(row, formatValue(value))
+				} else {
+					row = append(row, "-")
+				}
+			}
+		} else {
+			row = append(row, "-", "-")
+			for range percentiles {
+				row = append(row, "-")
+			}
+		}
+		if stat := proxy.PingMetrics.Statistics; stat != nil {
+			row = append(row, formatValue(stat.Mean))
+		} else {
+			row = append(row, "-")
+		}
+		row = append(row, formatValue(proxy.PingMetrics.Jitter))
+
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+
+	return b.String()
+}
+
+// SaveMarkdown saves the Markdown summary table
+func (r *Reporter) SaveMarkdown(result *BenchmarkResult, filepath string) error {
+	return os.WriteFile(filepath, []byte(r.GenerateMarkdown(result)), 0644)
+}
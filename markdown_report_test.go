@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateMarkdown(t *testing.T) {
+	markdown := NewReporter(&Config{}).GenerateMarkdown(tabularResult(t))
+	lines := strings.Split(strings.TrimSpace(markdown), "\n")
+
+	table := lines[len(lines)-4:]
+	if table[0] != "| Rank | Proxy | Score | Requests | Success % | Mean | Median | P50 | P99 | Ping mean | Jitter |" {
+		t.Errorf("unexpected header %q", table[0])
+	}
+	if !strings.HasPrefix(table[2], "| 1 | `socks://b.example:1080` | 100 | 2/2 | 100 | 200 | 200 |") {
+		t.Errorf("expected best ranked proxy first, got %q", table[2])
+	}
+	if !strings.HasPrefix(table[3], "| 2 | `http://a.example:8080` | 50 | 1/2 | 50 |") {
+		t.Errorf("unexpected second row %q", table[3])
+	}
+	if strings.Contains(markdown, "pass") {
+		t.Error("expected credentials to be left out of the table")
+	}
+}
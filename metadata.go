@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// resultSchemaVersion is the version of the result.json layout. It is bumped
+// whenever existing fields change meaning or are removed.
+const resultSchemaVersion = 1
+
+// version is the tool version, set at build time with
+// -ldflags "-X main.version=<version>"
+var version = "dev"
+
+// redacted replaces secrets in the recorded configuration
+const redacted = "REDACTED"
+
+// RunMetadata describes how and where a run was produced so results can be
+// reproduced and told apart later
+type RunMetadata struct {
+	RunID           string    `json:"run_id"`
+	ToolVersion     string    `json:"tool_version"`
+	StartTime       time.Time `json:"start_time"`
+	EndTime         time.Time `json:"end_time"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Hostname        string    `json:"hostname,omitempty"`
+	GoVersion       string    `json:"go_version"`
+	OS              string    `json:"os"`
+	Arch            string    `json:"arch"`
+	// Seed is the bootstrap resampling seed, if bootstrap intervals are configured
+	Seed *int64 `json:"seed,omitempty"`
+	// Config is the effective configuration after defaults and flags, with
+	// proxy passwords, tokens and credentials in URLs redacted
+	Config *Config `json:"config"`
+}
+
+// newRunMetadata records the environment and effective configuration of a run
+func newRunMetadata(config *Config, started, finished time.Time) *RunMetadata {
+	metadata := &RunMetadata{
+		RunID:           config.Output.RunID,
+		ToolVersion:     toolVersion(),
+		StartTime:       started,
+		EndTime:         finished,
+		DurationSeconds: finished.Sub(started).Seconds(),
+		GoVersion:       runtime.Version(),
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+		Config:          redactConfig(config),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		metadata.Hostname = hostname
+	}
+	if bootstrap := config.Statistics.Bootstrap; bootstrap != nil && bootstrap.Seed != nil {
+		seed := *bootstrap.Seed
+		metadata.Seed = &seed
+	}
+	return metadata
+}
+
+// toolVersion returns the version set at build time, falling back to the module
+// version recorded by go install
+func toolVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// redactConfig returns a deep copy of config with its secrets replaced
+func redactConfig(config *Config) *Config {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil
+	}
+	var copied Config
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil
+	}
+
+	for i, proxyString := range copied.Proxies {
+		copied.Proxies[i] = redactProxy(proxyString)
+	}
+	copied.Benchmark.TargetURL = redactURL(copied.Benchmark.TargetURL)
+	if influx := copied.Exporters.Influx; influx != nil {
+		influx.URL = redactURL(influx.URL)
+		if influx.Token != "" {
+			influx.Token = redacted
+		}
+		for name := range influx.Headers {
+			influx.Headers[name] = redacted
+		}
+	}
+	return &copied
+}
+
+// redactProxy replaces the password of a proxy string. A string that cannot be
+// parsed may still hold credentials, so everything up to its last "@" is
+// replaced, or the whole string if there is none.
+func redactProxy(proxyString string) string {
+	proxy, err := ParseProxy(proxyString)
+	if err != nil {
+		if at := strings.LastIndex(proxyString, "@"); at >= 0 {
+			return redacted + proxyString[at:]
+		}
+		return redacted
+	}
+	if proxy.Password == "" {
+		return proxyString
+	}
+	proxy.Password = redacted
+	return proxy.String()
+}
+
+// redactURL replaces the password and credential query parameters of a URL
+func redactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return raw
+	}
+
+	if parsed.User != nil {
+		if _, ok := parsed.User.Password(); ok {
+			parsed.User = url.UserPassword(parsed.User.Username(), redacted)
+		}
+	}
+	query := parsed.Query()
+	changed := false
+	for key := range query {
+		switch strings.ToLower(key) {
+		case "p", "password", "token", "access_token", "api_key", "apikey":
+			query.Set(key, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		parsed.RawQuery = query.Encode()
+	}
+	return parsed.String()
+}
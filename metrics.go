@@ -5,13 +5,27 @@ import (
 	"time"
 )
 
-// Metrics holds all metrics for a single proxy
+// Metrics holds all metrics for a single proxy. Durations are stored in
+// microseconds; reports convert them to the configured display unit.
 type Metrics struct {
-	ProxyString    string         `json:"proxy"`
-	RequestMetrics RequestMetrics `json:"request_metrics"`
-	PingMetrics    PingMetrics    `json:"ping_metrics"`
-	DerivedMetrics DerivedMetrics `json:"derived_metrics"`
-	mu             sync.Mutex
+	ProxyString      string                   `json:"proxy"`
+	RequestMetrics   RequestMetrics           `json:"request_metrics"`
+	PingMetrics      PingMetrics              `json:"ping_metrics"`
+	DerivedMetrics   DerivedMetrics           `json:"derived_metrics"`
+	TunnelMetrics    *TunnelMetrics           `json:"tunnel_metrics,omitempty"`
+	UDPMetrics       *UDPMetrics              `json:"udp_metrics,omitempty"`
+	WebSocketMetrics *WebSocketMetrics        `json:"websocket_metrics,omitempty"`
+	StabilityMetrics *StabilityMetrics        `json:"stability_metrics,omitempty"`
+	TimeSeries       []*TimeBucket            `json:"time_series,omitempty"`
+	Validation       []*ValidationCheckResult `json:"validation,omitempty"`
+	mu               sync.Mutex
+	dropSamples      bool
+	lastPing         int64
+	pingDiffTotal    int64
+	pingDiffs        int
+	timeSeriesStart  time.Time
+	timeSeriesWidth  time.Duration
+	timeSeriesFigs   int
 }
 
 // RequestMetrics holds request timing metrics
@@ -19,30 +33,154 @@ type RequestMetrics struct {
 	Total      int         `json:"total"`
 	Successful int         `json:"successful"`
 	Failed     int         `json:"failed"`
-	Times      []int64     `json:"times"`
+	Bytes      int64       `json:"bytes"`
+	Times      []int64     `json:"times_us"`
+	Histogram  *Histogram  `json:"histogram,omitempty"`
 	Statistics *Statistics `json:"statistics,omitempty"`
+
+	// Completion times in Unix nanoseconds, aligned with Times and FailedTimes
+	Timestamps       []int64 `json:"timestamps_unix_ns,omitempty"`
+	FailedTimestamps []int64 `json:"failed_timestamps_unix_ns,omitempty"`
+
+	// Durations of failed requests until they errored, overall and by error class.
+	// Like successful requests they go to histograms when those are enabled.
+	FailedTimes      []int64                    `json:"failed_times_us,omitempty"`
+	FailedHistogram  *Histogram                 `json:"failed_histogram,omitempty"`
+	FailedStatistics *Statistics                `json:"failed_statistics,omitempty"`
+	FailuresByClass  map[string]*FailureMetrics `json:"failures_by_class,omitempty"`
 }
 
-// PingMetrics holds ping timing metrics
-type PingMetrics struct {
-	Times      []int64     `json:"times"`
+// SuccessfulTime returns the total time spent in successful requests in
+// microseconds, from the histogram if the samples were not kept
+func (r *RequestMetrics) SuccessfulTime() float64 {
+	if r.Histogram != nil && len(r.Times) == 0 {
+		return r.Histogram.Sum()
+	}
+	var total int64
+	for _, t := range r.Times {
+		total += t
+	}
+	return float64(total)
+}
+
+// FailureMetrics holds the failed requests of one error class
+type FailureMetrics struct {
+	Count      int         `json:"count"`
+	Times      []int64     `json:"times_us"`
+	Histogram  *Histogram  `json:"histogram,omitempty"`
 	Statistics *Statistics `json:"statistics,omitempty"`
 }
 
+// PingMetrics holds ping timing metrics. Times are TCP connect times of successful
+// pings only; failed pings are counted by error class. In handshake mode
+// HandshakeTimes hold the protocol handshake measured on the same connection.
+type PingMetrics struct {
+	Attempts            int            `json:"attempts"`
+	Successful          int            `json:"successful"`
+	Failed              int            `json:"failed"`
+	FailuresByClass     map[string]int `json:"failures_by_class,omitempty"`
+	SuccessRate         float64        `json:"success_rate"`
+	LossPercent         float64        `json:"loss_percent"`
+	Jitter              float64        `json:"jitter"`
+	Times               []int64        `json:"times_us"`
+	Timestamps          []int64        `json:"timestamps_unix_ns,omitempty"`
+	Histogram           *Histogram     `json:"histogram,omitempty"`
+	Statistics          *Statistics    `json:"statistics,omitempty"`
+	HandshakeMode       string         `json:"handshake_mode,omitempty"`
+	HandshakeFailed     int            `json:"handshake_failed,omitempty"`
+	HandshakeTimes      []int64        `json:"handshake_times_us,omitempty"`
+	HandshakeStatistics *Statistics    `json:"handshake_statistics,omitempty"`
+}
+
 // DerivedMetrics holds derived timing metrics (request time - ping*2)
 type DerivedMetrics struct {
-	ProcessingTimes []int64     `json:"processing_times"`
+	ProcessingTimes []int64     `json:"processing_times_us"`
+	Histogram       *Histogram  `json:"histogram,omitempty"`
 	Statistics      *Statistics `json:"statistics,omitempty"`
 }
 
-// Statistics holds calculated statistical values
+// TunnelMetrics holds raw TCP tunnel timing metrics
+type TunnelMetrics struct {
+	EstablishTimes      []int64     `json:"establish_times_us"`
+	EstablishStatistics *Statistics `json:"establish_statistics,omitempty"`
+	EchoTimes           []int64     `json:"echo_times_us"`
+	EchoStatistics      *Statistics `json:"echo_statistics,omitempty"`
+}
+
+// UDPMetrics holds SOCKS5 UDP ASSOCIATE metrics
+type UDPMetrics struct {
+	Associations       int         `json:"associations"`
+	FailedAssociations int         `json:"failed_associations"`
+	SetupTimes         []int64     `json:"setup_times_us"`
+	SetupStatistics    *Statistics `json:"setup_statistics,omitempty"`
+	Sent               int         `json:"sent"`
+	Received           int         `json:"received"`
+	LossRate           float64     `json:"loss_rate"`
+	RTTs               []int64     `json:"rtts_us"`
+	RTTStatistics      *Statistics `json:"rtt_statistics,omitempty"`
+}
+
+// WebSocketMetrics holds WebSocket handshake, round-trip and drop metrics.
+// Connections that exchanged all their messages count as Survived; only
+// dropped connections contribute a time to drop.
+type WebSocketMetrics struct {
+	Connections          int         `json:"connections"`
+	FailedHandshakes     int         `json:"failed_handshakes"`
+	Drops                int         `json:"drops"`
+	Survived             int         `json:"survived"`
+	HandshakeTimes       []int64     `json:"handshake_times_us"`
+	HandshakeStatistics  *Statistics `json:"handshake_statistics,omitempty"`
+	RTTs                 []int64     `json:"rtts_us"`
+	RTTStatistics        *Statistics `json:"rtt_statistics,omitempty"`
+	TimeToDrop           []int64     `json:"time_to_drop_us"`
+	TimeToDropStatistics *Statistics `json:"time_to_drop_statistics,omitempty"`
+}
+
+// StabilityMetrics holds long-lived tunnel stability metrics
+type StabilityMetrics struct {
+	Tunnels              int                `json:"tunnels"`
+	FailedTunnels        int                `json:"failed_tunnels"`
+	Drops                int                `json:"drops"`
+	TimeToDrop           []int64            `json:"time_to_drop_us"`
+	TimeToDropStatistics *Statistics        `json:"time_to_drop_statistics,omitempty"`
+	IdleProbes           []IdleProbeResult  `json:"idle_probes,omitempty"`
+	IdleTimeout          *IdleTimeoutResult `json:"idle_timeout,omitempty"`
+}
+
+// IdleProbeResult records whether a tunnel survived being idle for IdleMs
+type IdleProbeResult struct {
+	IdleMs   int64 `json:"idle_ms"`
+	Survived bool  `json:"survived"`
+}
+
+// IdleTimeoutResult brackets the detected idle timeout between the longest idle
+// period a tunnel survived and the shortest one that killed it
+type IdleTimeoutResult struct {
+	SurvivedMs int64 `json:"survived_ms"`
+	KilledMs   int64 `json:"killed_ms"`
+}
+
+// Statistics holds calculated statistical values in the unit of the samples
 type Statistics struct {
-	Min         int64               `json:"min"`
-	Max         int64               `json:"max"`
-	Mean        float64             `json:"mean,omitempty"`
-	Median      float64             `json:"median,omitempty"`
-	StdDev      float64             `json:"std_dev"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Mean        float64            `json:"mean,omitempty"`
+	Median      float64            `json:"median,omitempty"`
+	StdDev      float64            `json:"std_dev"`
 	Percentiles map[string]float64 `json:"percentiles,omitempty"`
+
+	// Bootstrap confidence intervals keyed by "mean", "median" or "p<percentile>"
+	ConfidenceLevel     float64                        `json:"confidence_level,omitempty"`
+	ConfidenceIntervals map[string]*ConfidenceInterval `json:"confidence_intervals,omitempty"`
+
+	// Outlier detection and robust statistics, if enabled
+	Robust *RobustStatistics `json:"robust,omitempty"`
+}
+
+// ConfidenceInterval is the range a statistic falls in at the confidence level
+type ConfidenceInterval struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
 }
 
 // NewMetrics creates a new Metrics instance for a proxy
@@ -61,34 +199,519 @@ func NewMetrics(proxyString string) *Metrics {
 	}
 }
 
-// AddRequestTime adds a request time measurement
+// EnableHistograms makes request, ping and derived times also be recorded in
+// histograms. Unless keepSamples is set the raw sample slices stay empty, which
+// bounds memory for very long runs.
+func (m *Metrics) EnableHistograms(significantFigures int, keepSamples bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.RequestMetrics.Histogram = NewHistogram(significantFigures)
+	m.PingMetrics.Histogram = NewHistogram(significantFigures)
+	m.DerivedMetrics.Histogram = NewHistogram(significantFigures)
+	m.RequestMetrics.FailedHistogram = NewHistogram(significantFigures)
+	m.dropSamples = !keepSamples
+}
+
+// ValidationCheckResult counts how often one response validation check passed
+type ValidationCheckResult struct {
+	Path      string `json:"path"`
+	Type      string `json:"type"`
+	Passed    int    `json:"passed"`
+	Failed    int    `json:"failed"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// TimeBucket holds the requests completed during one interval of the run.
+// Statistics cover the successful requests only.
+type TimeBucket struct {
+	Start      time.Time   `json:"start"`
+	Count      int         `json:"count"`
+	Successful int         `json:"successful"`
+	Failed     int         `json:"failed"`
+	ErrorRate  float64     `json:"error_rate"`
+	Statistics *Statistics `json:"statistics,omitempty"`
+	histogram  *Histogram
+}
+
+// EnableTimeSeries makes requests also be bucketed by completion time into
+// intervals of width starting at start. Each bucket keeps a histogram so memory
+// is bounded by the run length rather than the number of requests.
+func (m *Metrics) EnableTimeSeries(start time.Time, width time.Duration, significantFigures int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.timeSeriesStart = start
+	m.timeSeriesWidth = width
+	m.timeSeriesFigs = significantFigures
+	m.TimeSeries = make([]*TimeBucket, 0)
+}
+
+// timeBucket returns the bucket for a request completed at, creating it and any
+// empty buckets before it. Must be called with m.mu held.
+func (m *Metrics) timeBucket(at time.Time) *TimeBucket {
+	index := 0
+	if elapsed := at.Sub(m.timeSeriesStart); elapsed > 0 {
+		index = int(elapsed / m.timeSeriesWidth)
+	}
+	for len(m.TimeSeries) <= index {
+		m.TimeSeries = append(m.TimeSeries, &TimeBucket{
+			Start:     m.timeSeriesStart.Add(time.Duration(len(m.TimeSeries)) * m.timeSeriesWidth),
+			histogram: NewHistogram(m.timeSeriesFigs),
+		})
+	}
+	return m.TimeSeries[index]
+}
+
+// HasSamples reports whether raw request and ping samples are being kept
+func (m *Metrics) HasSamples() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return !m.dropSamples
+}
+
+// AddRequestTime adds a request time measurement. Failures recorded this way
+// have no error and are classed as "other"; prefer AddRequestFailure.
 func (m *Metrics) AddRequestTime(duration time.Duration, success bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if !success {
+		m.addRequestFailure(duration, nil)
+		return
+	}
+
+	now := time.Now()
 	m.RequestMetrics.Total++
+	m.RequestMetrics.Successful++
+	m.recordTimeBucket(now, duration, true)
+	if m.RequestMetrics.Histogram != nil {
+		m.RequestMetrics.Histogram.RecordDuration(duration)
+	}
+	if !m.dropSamples {
+		m.RequestMetrics.Times = append(m.RequestMetrics.Times, duration.Microseconds())
+		m.RequestMetrics.Timestamps = append(m.RequestMetrics.Timestamps, now.UnixNano())
+	}
+}
+
+// AddRequestFailure records how long a failed request took before erroring,
+// both in the overall failed series and under its error class
+func (m *Metrics) AddRequestFailure(duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addRequestFailure(duration, err)
+}
+
+// addRequestFailure records a failed request. Callers hold m.mu.
+func (m *Metrics) addRequestFailure(duration time.Duration, err error) {
+	class := classifyError(err)
+	if class == "" {
+		class = ErrorClassOther
+	}
+	if m.RequestMetrics.FailuresByClass == nil {
+		m.RequestMetrics.FailuresByClass = make(map[string]*FailureMetrics)
+	}
+	failures, ok := m.RequestMetrics.FailuresByClass[class]
+	if !ok {
+		failures = &FailureMetrics{Times: make([]int64, 0)}
+		if histogram := m.RequestMetrics.FailedHistogram; histogram != nil {
+			failures.Histogram = NewHistogram(histogram.significantFigures)
+		}
+		m.RequestMetrics.FailuresByClass[class] = failures
+	}
+
+	now := time.Now()
+	m.RequestMetrics.Total++
+	m.RequestMetrics.Failed++
+	failures.Count++
+	if m.RequestMetrics.FailedHistogram != nil {
+		m.RequestMetrics.FailedHistogram.RecordDuration(duration)
+		failures.Histogram.RecordDuration(duration)
+	}
+	if !m.dropSamples {
+		m.RequestMetrics.FailedTimes = append(m.RequestMetrics.FailedTimes, duration.Microseconds())
+		m.RequestMetrics.FailedTimestamps = append(m.RequestMetrics.FailedTimestamps, now.UnixNano())
+		failures.Times = append(failures.Times, duration.Microseconds())
+	}
+	m.recordTimeBucket(now, duration, false)
+}
+
+// recordTimeBucket counts a request completed at in its time bucket, if time
+// series are enabled. Callers hold m.mu.
+func (m *Metrics) recordTimeBucket(at time.Time, duration time.Duration, success bool) {
+	if m.timeSeriesWidth <= 0 {
+		return
+	}
+
+	bucket := m.timeBucket(at)
+	bucket.Count++
 	if success {
-		m.RequestMetrics.Successful++
-		m.RequestMetrics.Times = append(m.RequestMetrics.Times, duration.Milliseconds())
+		bucket.Successful++
+		bucket.histogram.RecordDuration(duration)
 	} else {
-		m.RequestMetrics.Failed++
+		bucket.Failed++
 	}
+	bucket.ErrorRate = float64(bucket.Failed) / float64(bucket.Count)
 }
 
-// AddPingTime adds a ping time measurement
+// AddResponseBytes adds the size of a successful response
+func (m *Metrics) AddResponseBytes(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.RequestMetrics.Bytes += int64(n)
+}
+
+// AddValidationResults records the outcome of each validation check for one
+// response; results[i] is nil if checks[i] passed
+func (m *Metrics) AddValidationResults(checks []ValidationCheck, results []error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Validation == nil {
+		m.Validation = make([]*ValidationCheckResult, len(checks))
+		for i, check := range checks {
+			m.Validation[i] = &ValidationCheckResult{Path: check.Path, Type: check.Type}
+		}
+	}
+	for i, err := range results {
+		if i >= len(m.Validation) {
+			break
+		}
+		if err != nil {
+			m.Validation[i].Failed++
+			m.Validation[i].LastError = err.Error()
+		} else {
+			m.Validation[i].Passed++
+		}
+	}
+}
+
+// AddPingTime adds a successful ping time measurement
 func (m *Metrics) AddPingTime(duration time.Duration) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.PingMetrics.Times = append(m.PingMetrics.Times, duration.Milliseconds())
+	// Successive differences are summed as pings arrive so jitter does not
+	// depend on the samples being kept
+	micros := duration.Microseconds()
+	if m.PingMetrics.Successful > 0 {
+		diff := micros - m.lastPing
+		if diff < 0 {
+			diff = -diff
+		}
+		m.pingDiffTotal += diff
+		m.pingDiffs++
+	}
+	m.lastPing = micros
+
+	m.PingMetrics.Attempts++
+	m.PingMetrics.Successful++
+	if m.PingMetrics.Histogram != nil {
+		m.PingMetrics.Histogram.RecordDuration(duration)
+	}
+	if !m.dropSamples {
+		m.PingMetrics.Times = append(m.PingMetrics.Times, micros)
+		m.PingMetrics.Timestamps = append(m.PingMetrics.Timestamps, time.Now().UnixNano())
+	}
+	m.updatePingRates()
 }
 
-// AddDerivedTime adds a derived processing time measurement
+// AddPingFailure counts a failed ping under its error class. Failed pings are kept
+// out of the latency samples so they do not drag the statistics down.
+func (m *Metrics) AddPingFailure(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.PingMetrics.FailuresByClass == nil {
+		m.PingMetrics.FailuresByClass = make(map[string]int)
+	}
+	m.PingMetrics.Attempts++
+	m.PingMetrics.Failed++
+	m.PingMetrics.FailuresByClass[classifyError(err)]++
+	m.updatePingRates()
+}
+
+// updatePingRates recalculates ping success rate and loss. Callers hold m.mu.
+func (m *Metrics) updatePingRates() {
+	m.PingMetrics.SuccessRate = float64(m.PingMetrics.Successful) / float64(m.PingMetrics.Attempts) * 100
+	m.PingMetrics.LossPercent = 100 - m.PingMetrics.SuccessRate
+}
+
+// AddPingHandshakeTime adds a protocol handshake ping measurement
+func (m *Metrics) AddPingHandshakeTime(mode string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.PingMetrics.HandshakeMode = mode
+	m.PingMetrics.HandshakeTimes = append(m.PingMetrics.HandshakeTimes, duration.Microseconds())
+}
+
+// AddPingHandshakeFailure counts a failed protocol handshake ping
+func (m *Metrics) AddPingHandshakeFailure(mode string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.PingMetrics.HandshakeMode = mode
+	m.PingMetrics.HandshakeFailed++
+}
+
+// AddDerivedTime adds a derived processing time measurement in microseconds
 func (m *Metrics) AddDerivedTime(duration int64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.DerivedMetrics.ProcessingTimes = append(m.DerivedMetrics.ProcessingTimes, duration)
+	if m.DerivedMetrics.Histogram != nil {
+		m.DerivedMetrics.Histogram.Record(duration)
+	}
+	if !m.dropSamples {
+		m.DerivedMetrics.ProcessingTimes = append(m.DerivedMetrics.ProcessingTimes, duration)
+	}
+}
+
+// DeriveHistogram estimates the derived histogram when raw samples are not kept,
+// shifting the request histogram by twice the mean ping time
+func (m *Metrics) DeriveHistogram() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.RequestMetrics.Histogram == nil || m.PingMetrics.Histogram == nil {
+		return
+	}
+	offset := -int64(2 * m.PingMetrics.Histogram.Mean())
+	m.DerivedMetrics.Histogram = m.RequestMetrics.Histogram.Shifted(offset)
+}
+
+// AddTunnelTime adds a tunnel establishment measurement and, if data was
+// exchanged through the tunnel, its echo round-trip time
+func (m *Metrics) AddTunnelTime(establish, echo time.Duration, exchanged bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.TunnelMetrics == nil {
+		m.TunnelMetrics = &TunnelMetrics{
+			EstablishTimes: make([]int64, 0),
+			EchoTimes:      make([]int64, 0),
+		}
+	}
+	m.TunnelMetrics.EstablishTimes = append(m.TunnelMetrics.EstablishTimes, establish.Microseconds())
+	if exchanged {
+		m.TunnelMetrics.EchoTimes = append(m.TunnelMetrics.EchoTimes, echo.Microseconds())
+	}
+}
+
+// AddUDPAssociation records the outcome and setup time of a UDP association
+func (m *Metrics) AddUDPAssociation(setup time.Duration, success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	udp := m.udpMetrics()
+	udp.Associations++
+	if success {
+		udp.SetupTimes = append(udp.SetupTimes, setup.Microseconds())
+	} else {
+		udp.FailedAssociations++
+	}
+}
+
+// AddUDPDatagram records a sent datagram and its round-trip time if echoed
+func (m *Metrics) AddUDPDatagram(rtt time.Duration, received bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	udp := m.udpMetrics()
+	udp.Sent++
+	if received {
+		udp.Received++
+		udp.RTTs = append(udp.RTTs, rtt.Microseconds())
+	}
+	udp.LossRate = float64(udp.Sent-udp.Received) / float64(udp.Sent)
+}
+
+// udpMetrics returns the UDP metrics, creating them on first use. Callers hold m.mu.
+func (m *Metrics) udpMetrics() *UDPMetrics {
+	if m.UDPMetrics == nil {
+		m.UDPMetrics = &UDPMetrics{
+			SetupTimes: make([]int64, 0),
+			RTTs:       make([]int64, 0),
+		}
+	}
+	return m.UDPMetrics
+}
+
+// AddWebSocketHandshake records a WebSocket connection attempt and its handshake time
+func (m *Metrics) AddWebSocketHandshake(handshake time.Duration, success bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ws := m.webSocketMetrics()
+	ws.Connections++
+	if success {
+		ws.HandshakeTimes = append(ws.HandshakeTimes, handshake.Microseconds())
+	} else {
+		ws.FailedHandshakes++
+	}
+}
+
+// AddWebSocketRTT records a WebSocket message round-trip time
+func (m *Metrics) AddWebSocketRTT(rtt time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ws := m.webSocketMetrics()
+	ws.RTTs = append(ws.RTTs, rtt.Microseconds())
+}
+
+// AddWebSocketLifetime records how a connection ended: dropped connections
+// add their time to drop, the others count as survived
+func (m *Metrics) AddWebSocketLifetime(lifetime time.Duration, dropped bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ws := m.webSocketMetrics()
+	if dropped {
+		ws.Drops++
+		ws.TimeToDrop = append(ws.TimeToDrop, lifetime.Microseconds())
+	} else {
+		ws.Survived++
+	}
+}
+
+// webSocketMetrics returns the WebSocket metrics, creating them on first use. Callers hold m.mu.
+func (m *Metrics) webSocketMetrics() *WebSocketMetrics {
+	if m.WebSocketMetrics == nil {
+		m.WebSocketMetrics = &WebSocketMetrics{
+			HandshakeTimes: make([]int64, 0),
+			RTTs:           make([]int64, 0),
+			TimeToDrop:     make([]int64, 0),
+		}
+	}
+	return m.WebSocketMetrics
+}
+
+// AddStabilityTunnel records the outcome of a held tunnel
+func (m *Metrics) AddStabilityTunnel(lifetime time.Duration, opened, dropped bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stability := m.stabilityMetrics()
+	stability.Tunnels++
+	if !opened {
+		stability.FailedTunnels++
+		return
+	}
+	if dropped {
+		stability.Drops++
+		stability.TimeToDrop = append(stability.TimeToDrop, lifetime.Microseconds())
+	}
+}
+
+// AddIdleProbe records whether a tunnel survived an idle period and updates the
+// detected idle timeout
+func (m *Metrics) AddIdleProbe(idle time.Duration, survived bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stability := m.stabilityMetrics()
+	stability.IdleProbes = append(stability.IdleProbes, IdleProbeResult{
+		IdleMs:   idle.Milliseconds(),
+		Survived: survived,
+	})
+
+	var killed, survivedMs int64
+	for _, probe := range stability.IdleProbes {
+		if !probe.Survived && (killed == 0 || probe.IdleMs < killed) {
+			killed = probe.IdleMs
+		}
+	}
+	if killed == 0 {
+		stability.IdleTimeout = nil
+		return
+	}
+	for _, probe := range stability.IdleProbes {
+		if probe.Survived && probe.IdleMs < killed && probe.IdleMs > survivedMs {
+			survivedMs = probe.IdleMs
+		}
+	}
+	stability.IdleTimeout = &IdleTimeoutResult{
+		SurvivedMs: survivedMs,
+		KilledMs:   killed,
+	}
+}
+
+// stabilityMetrics returns the stability metrics, creating them on first use. Callers hold m.mu.
+func (m *Metrics) stabilityMetrics() *StabilityMetrics {
+	if m.StabilityMetrics == nil {
+		m.StabilityMetrics = &StabilityMetrics{
+			TimeToDrop: make([]int64, 0),
+		}
+	}
+	return m.StabilityMetrics
+}
+
+// MetricsSnapshot is a consistent copy of a proxy's counters and latency
+// distributions, safe to read while the benchmark is still running
+type MetricsSnapshot struct {
+	ProxyString            string
+	Requests               int
+	RequestsSuccessful     int
+	RequestsFailed         int
+	ResponseBytes          int64
+	RequestFailuresByClass map[string]int
+	PingAttempts           int
+	PingSuccessful         int
+	PingFailed             int
+	PingFailuresByClass    map[string]int
+	RequestLatency         *Histogram
+	PingLatency            *Histogram
+	DerivedLatency         *Histogram
+}
+
+// Snapshot copies the counters and latency distributions. Distributions come
+// from the histograms if enabled and are built from the samples otherwise.
+func (m *Metrics) Snapshot() *MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := &MetricsSnapshot{
+		ProxyString:            m.ProxyString,
+		Requests:               m.RequestMetrics.Total,
+		RequestsSuccessful:     m.RequestMetrics.Successful,
+		RequestsFailed:         m.RequestMetrics.Failed,
+		ResponseBytes:          m.RequestMetrics.Bytes,
+		RequestFailuresByClass: make(map[string]int, len(m.RequestMetrics.FailuresByClass)),
+		PingAttempts:           m.PingMetrics.Attempts,
+		PingSuccessful:         m.PingMetrics.Successful,
+		PingFailed:             m.PingMetrics.Failed,
+		PingFailuresByClass:    make(map[string]int, len(m.PingMetrics.FailuresByClass)),
+		RequestLatency:         snapshotDistribution(m.RequestMetrics.Histogram, m.RequestMetrics.Times),
+		PingLatency:            snapshotDistribution(m.PingMetrics.Histogram, m.PingMetrics.Times),
+		DerivedLatency:         snapshotDistribution(m.DerivedMetrics.Histogram, m.DerivedMetrics.ProcessingTimes),
+	}
+	for class, failures := range m.RequestMetrics.FailuresByClass {
+		snapshot.RequestFailuresByClass[class] = failures.Count
+	}
+	for class, count := range m.PingMetrics.FailuresByClass {
+		snapshot.PingFailuresByClass[class] = count
+	}
+	return snapshot
+}
+
+// snapshotDistribution copies histogram, or records samples into a new one
+func snapshotDistribution(histogram *Histogram, samples []int64) *Histogram {
+	if histogram != nil {
+		distribution := NewHistogram(histogram.significantFigures)
+		distribution.Merge(histogram)
+		return distribution
+	}
+
+	distribution := NewHistogram(3)
+	for _, sample := range samples {
+		distribution.Record(sample)
+	}
+	return distribution
 }
 
 // GetRequestTimes returns a copy of request times
@@ -101,6 +724,33 @@ func (m *Metrics) GetRequestTimes() []int64 {
 	return times
 }
 
+// GetFailedRequestTimes returns a copy of all failed request times and of the
+// failed request times per error class
+func (m *Metrics) GetFailedRequestTimes() ([]int64, map[string][]int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	failed := make([]int64, len(m.RequestMetrics.FailedTimes))
+	copy(failed, m.RequestMetrics.FailedTimes)
+	byClass := make(map[string][]int64, len(m.RequestMetrics.FailuresByClass))
+	for class, failures := range m.RequestMetrics.FailuresByClass {
+		times := make([]int64, len(failures.Times))
+		copy(times, failures.Times)
+		byClass[class] = times
+	}
+	return failed, byClass
+}
+
+// GetTimeSeries returns a copy of the time buckets
+func (m *Metrics) GetTimeSeries() []*TimeBucket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buckets := make([]*TimeBucket, len(m.TimeSeries))
+	copy(buckets, m.TimeSeries)
+	return buckets
+}
+
 // GetPingTimes returns a copy of ping times
 func (m *Metrics) GetPingTimes() []int64 {
 	m.mu.Lock()
@@ -111,6 +761,28 @@ func (m *Metrics) GetPingTimes() []int64 {
 	return times
 }
 
+// GetPingJitter returns the mean absolute difference between successive ping
+// times, computed as they were recorded
+func (m *Metrics) GetPingJitter() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.pingDiffs == 0 {
+		return 0
+	}
+	return float64(m.pingDiffTotal) / float64(m.pingDiffs)
+}
+
+// GetPingHandshakeTimes returns a copy of ping handshake times
+func (m *Metrics) GetPingHandshakeTimes() []int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	times := make([]int64, len(m.PingMetrics.HandshakeTimes))
+	copy(times, m.PingMetrics.HandshakeTimes)
+	return times
+}
+
 // GetDerivedTimes returns a copy of derived processing times
 func (m *Metrics) GetDerivedTimes() []int64 {
 	m.mu.Lock()
@@ -120,3 +792,63 @@ func (m *Metrics) GetDerivedTimes() []int64 {
 	copy(times, m.DerivedMetrics.ProcessingTimes)
 	return times
 }
+
+// GetTunnelTimes returns copies of tunnel establishment and echo times
+func (m *Metrics) GetTunnelTimes() ([]int64, []int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.TunnelMetrics == nil {
+		return nil, nil
+	}
+	establish := make([]int64, len(m.TunnelMetrics.EstablishTimes))
+	copy(establish, m.TunnelMetrics.EstablishTimes)
+	echo := make([]int64, len(m.TunnelMetrics.EchoTimes))
+	copy(echo, m.TunnelMetrics.EchoTimes)
+	return establish, echo
+}
+
+// GetUDPTimes returns copies of UDP association setup times and datagram RTTs
+func (m *Metrics) GetUDPTimes() ([]int64, []int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.UDPMetrics == nil {
+		return nil, nil
+	}
+	setup := make([]int64, len(m.UDPMetrics.SetupTimes))
+	copy(setup, m.UDPMetrics.SetupTimes)
+	rtts := make([]int64, len(m.UDPMetrics.RTTs))
+	copy(rtts, m.UDPMetrics.RTTs)
+	return setup, rtts
+}
+
+// GetWebSocketTimes returns copies of WebSocket handshake times, RTTs and times to drop
+func (m *Metrics) GetWebSocketTimes() ([]int64, []int64, []int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.WebSocketMetrics == nil {
+		return nil, nil, nil
+	}
+	handshakes := make([]int64, len(m.WebSocketMetrics.HandshakeTimes))
+	copy(handshakes, m.WebSocketMetrics.HandshakeTimes)
+	rtts := make([]int64, len(m.WebSocketMetrics.RTTs))
+	copy(rtts, m.WebSocketMetrics.RTTs)
+	timeToDrop := make([]int64, len(m.WebSocketMetrics.TimeToDrop))
+	copy(timeToDrop, m.WebSocketMetrics.TimeToDrop)
+	return handshakes, rtts, timeToDrop
+}
+
+// GetTimeToDrop returns a copy of stability tunnel time-to-drop values
+func (m *Metrics) GetTimeToDrop() []int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.StabilityMetrics == nil {
+		return nil
+	}
+	times := make([]int64, len(m.StabilityMetrics.TimeToDrop))
+	copy(times, m.StabilityMetrics.TimeToDrop)
+	return times
+}
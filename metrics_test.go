@@ -1,6 +1,9 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -69,11 +72,11 @@ func TestStatisticsCalculation(t *testing.T) {
 	}
 	
 	if stats.Min != 100 {
-		t.Errorf("Expected Min=100, got %d", stats.Min)
+		t.Errorf("Expected Min=100, got %v", stats.Min)
 	}
 	
 	if stats.Max != 200 {
-		t.Errorf("Expected Max=200, got %d", stats.Max)
+		t.Errorf("Expected Max=200, got %v", stats.Max)
 	}
 	
 	// Check that mean and median were calculated
@@ -89,4 +92,241 @@ func TestStatisticsCalculation(t *testing.T) {
 	if len(stats.Percentiles) == 0 {
 		t.Error("Expected percentiles to be calculated")
 	}
-}
\ No newline at end of file
+}
+func TestPingFailureTracking(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.AddPingTime(40 * time.Millisecond)
+	metrics.AddPingFailure(context.DeadlineExceeded)
+	metrics.AddPingTime(60 * time.Millisecond)
+	metrics.AddPingFailure(syscall.ECONNREFUSED)
+
+	ping := metrics.PingMetrics
+	if ping.Attempts != 4 || ping.Successful != 2 || ping.Failed != 2 {
+		t.Errorf("unexpected ping counts: attempts=%d successful=%d failed=%d", ping.Attempts, ping.Successful, ping.Failed)
+	}
+	if ping.SuccessRate != 50 || ping.LossPercent != 50 {
+		t.Errorf("expected 50%% success and loss, got %v / %v", ping.SuccessRate, ping.LossPercent)
+	}
+	if ping.FailuresByClass[ErrorClassTimeout] != 1 || ping.FailuresByClass[ErrorClassConnectionRefused] != 1 {
+		t.Errorf("unexpected failure classes: %v", ping.FailuresByClass)
+	}
+
+	UpdateMetricsStatistics(metrics, &StatisticsConfig{Mean: true})
+	if metrics.PingMetrics.Statistics.Min != 40000 {
+		t.Errorf("expected failed pings to be excluded from statistics, got Min=%v", metrics.PingMetrics.Statistics.Min)
+	}
+	if metrics.PingMetrics.Jitter != 20000 {
+		t.Errorf("expected jitter 20000us, got %v", metrics.PingMetrics.Jitter)
+	}
+}
+
+func TestCalculateJitter(t *testing.T) {
+	if jitter := CalculateJitter([]int64{10, 20, 15, 15}); jitter != 5 {
+		t.Errorf("expected jitter 5, got %v", jitter)
+	}
+	if jitter := CalculateJitter([]int64{10}); jitter != 0 {
+		t.Errorf("expected jitter 0 for a single sample, got %v", jitter)
+	}
+}
+
+func TestSubMillisecondResolution(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.AddRequestTime(250*time.Microsecond, true)
+	metrics.AddRequestTime(750*time.Microsecond, true)
+
+	UpdateMetricsStatistics(metrics, &StatisticsConfig{Mean: true})
+	if metrics.RequestMetrics.Statistics.Mean != 500 {
+		t.Errorf("expected mean of 500us, got %v", metrics.RequestMetrics.Statistics.Mean)
+	}
+
+	reporter := NewReporter(&Config{Report: ReportConfig{Unit: "ms"}})
+	report := reporter.GenerateReport(map[string]*Metrics{"test-proxy": metrics})
+	if mean := report.Proxies[0].RequestMetrics.Statistics.Mean; mean != 0.5 {
+		t.Errorf("expected mean of 0.5ms in report, got %v", mean)
+	}
+	if report.Unit != "ms" {
+		t.Errorf("expected report unit ms, got %s", report.Unit)
+	}
+	if metrics.RequestMetrics.Statistics.Mean != 500 {
+		t.Error("report generation must not modify the stored statistics")
+	}
+}
+
+func TestBootstrapConfidenceIntervals(t *testing.T) {
+	values := []int64{100, 120, 95, 130, 110, 105, 150, 98, 115, 125}
+	seed := int64(42)
+	config := &StatisticsConfig{
+		Percentiles: []float64{90},
+		Mean:        true,
+		Median:      true,
+		Bootstrap:   &BootstrapConfig{Enabled: true, Level: 0.95, Resamples: 500, Seed: &seed},
+	}
+
+	stats := CalculateStatistics(values, config)
+	for _, key := range []string{"mean", "median", "p90.0"} {
+		interval, ok := stats.ConfidenceIntervals[key]
+		if !ok {
+			t.Fatalf("missing confidence interval for %s", key)
+		}
+		if interval.Lower > interval.Upper {
+			t.Errorf("%s: lower bound %v above upper bound %v", key, interval.Lower, interval.Upper)
+		}
+	}
+	mean := stats.ConfidenceIntervals["mean"]
+	if stats.Mean < mean.Lower || stats.Mean > mean.Upper {
+		t.Errorf("mean %v outside its interval [%v, %v]", stats.Mean, mean.Lower, mean.Upper)
+	}
+
+	again := CalculateStatistics(values, config)
+	if *again.ConfidenceIntervals["mean"] != *mean {
+		t.Error("expected identical intervals for the same seed")
+	}
+}
+
+func TestTimeSeriesBuckets(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.EnableTimeSeries(time.Now().Add(-90*time.Second), time.Minute, 3)
+
+	metrics.AddRequestTime(100*time.Millisecond, true)
+	metrics.AddRequestTime(300*time.Millisecond, true)
+	metrics.AddRequestTime(0, false)
+	UpdateMetricsStatistics(metrics, &StatisticsConfig{Mean: true, Median: true, Percentiles: []float64{95}})
+
+	buckets := metrics.GetTimeSeries()
+	if len(buckets) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(buckets))
+	}
+	if buckets[0].Count != 0 || buckets[0].Statistics != nil {
+		t.Errorf("expected empty first bucket, got %+v", buckets[0])
+	}
+	current := buckets[1]
+	if current.Count != 3 || current.Failed != 1 {
+		t.Errorf("expected 3 requests with 1 failure, got %d / %d", current.Count, current.Failed)
+	}
+	if current.ErrorRate < 0.33 || current.ErrorRate > 0.34 {
+		t.Errorf("expected error rate 1/3, got %v", current.ErrorRate)
+	}
+	if current.Statistics == nil || current.Statistics.Mean != 200000 {
+		t.Errorf("expected bucket mean 200000us, got %+v", current.Statistics)
+	}
+	if !buckets[1].Start.Equal(buckets[0].Start.Add(time.Minute)) {
+		t.Error("expected consecutive buckets one width apart")
+	}
+}
+
+func TestTimeSeriesStartsWithRequestPhase(t *testing.T) {
+	config := &Config{Statistics: StatisticsConfig{
+		TimeSeries: &TimeSeriesConfig{Enabled: true, BucketWidthMs: 60000},
+	}}
+	metrics := NewMetrics("test-proxy")
+	engine := &BenchmarkEngine{config: config, metrics: map[string]*Metrics{metrics.ProxyString: metrics}}
+
+	// Requests before the request phase, such as warmup, are not bucketed
+	metrics.AddRequestTime(time.Millisecond, true)
+	if len(metrics.GetTimeSeries()) != 0 {
+		t.Fatal("expected no buckets before the request phase")
+	}
+
+	before := time.Now()
+	engine.startTimeSeries()
+	metrics.AddRequestTime(time.Millisecond, true)
+	buckets := metrics.GetTimeSeries()
+	if len(buckets) != 1 || buckets[0].Count != 1 {
+		t.Fatalf("expected one bucket with the request, got %+v", buckets)
+	}
+	if buckets[0].Start.Before(before) {
+		t.Errorf("expected the first bucket to start with the request phase, got %v before %v", buckets[0].Start, before)
+	}
+}
+
+func TestRequestFailureTimesByClass(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.AddRequestTime(100*time.Millisecond, true)
+	metrics.AddRequestFailure(5*time.Millisecond, errors.New("proxy returned 407 Proxy Authentication Required"))
+	metrics.AddRequestFailure(30*time.Second, context.DeadlineExceeded)
+	metrics.AddRequestFailure(29*time.Second, context.DeadlineExceeded)
+	metrics.AddRequestTime(time.Millisecond, false)
+	UpdateMetricsStatistics(metrics, &StatisticsConfig{Mean: true, Median: true})
+
+	request := metrics.RequestMetrics
+	if request.Total != 5 || request.Failed != 4 || len(request.FailedTimes) != 4 {
+		t.Fatalf("expected 5 total / 4 failed with 4 failed samples, got %d / %d / %d", request.Total, request.Failed, len(request.FailedTimes))
+	}
+	if len(request.Times) != 1 {
+		t.Errorf("expected failed durations kept out of Times, got %v", request.Times)
+	}
+
+	timeouts := request.FailuresByClass[ErrorClassTimeout]
+	if timeouts == nil || timeouts.Count != 2 || timeouts.Statistics.Mean != 29500000 {
+		t.Errorf("expected 2 timeouts with mean 29500000us, got %+v", timeouts)
+	}
+	if auth := request.FailuresByClass[ErrorClassAuth]; auth == nil || auth.Statistics.Max != 5000 {
+		t.Errorf("expected auth failure of 5000us, got %+v", auth)
+	}
+	if other := request.FailuresByClass[ErrorClassOther]; other == nil || other.Count != 1 {
+		t.Errorf("expected failure without error classed as other, got %+v", other)
+	}
+	if request.FailedStatistics == nil || request.FailedStatistics.Min != 1000 {
+		t.Errorf("expected failed statistics min 1000us, got %+v", request.FailedStatistics)
+	}
+}
+
+func TestRequestFailuresInHistogramMode(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.EnableHistograms(3, false)
+	metrics.AddRequestTime(10*time.Millisecond, true)
+	metrics.AddRequestFailure(2*time.Second, context.DeadlineExceeded)
+	metrics.AddRequestFailure(4*time.Second, context.DeadlineExceeded)
+	UpdateMetricsStatistics(metrics, &StatisticsConfig{Mean: true})
+
+	request := metrics.RequestMetrics
+	if len(request.FailedTimes) != 0 {
+		t.Errorf("expected no failed samples when samples are dropped, got %v", request.FailedTimes)
+	}
+	if request.FailedHistogram == nil || request.FailedHistogram.Count() != 2 {
+		t.Fatalf("expected both failures in the failed histogram, got %+v", request.FailedHistogram)
+	}
+	timeouts := request.FailuresByClass[ErrorClassTimeout]
+	if timeouts == nil || len(timeouts.Times) != 0 || timeouts.Histogram == nil || timeouts.Histogram.Count() != 2 {
+		t.Fatalf("expected timeouts recorded in their histogram only, got %+v", timeouts)
+	}
+	if request.FailedStatistics == nil || request.FailedStatistics.Max < 3990000 {
+		t.Errorf("expected failed statistics from the histogram, got %+v", request.FailedStatistics)
+	}
+}
+
+func TestPingJitterWithoutSamples(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.EnableHistograms(3, false)
+	for _, ms := range []int{10, 20, 15, 15} {
+		metrics.AddPingTime(time.Duration(ms) * time.Millisecond)
+	}
+	metrics.AddPingFailure(context.DeadlineExceeded)
+	UpdateMetricsStatistics(metrics, &StatisticsConfig{Mean: true})
+
+	if len(metrics.PingMetrics.Times) != 0 {
+		t.Fatalf("expected ping samples to be dropped, got %v", metrics.PingMetrics.Times)
+	}
+	if metrics.PingMetrics.Jitter != 5000 {
+		t.Errorf("expected jitter 5000us, got %v", metrics.PingMetrics.Jitter)
+	}
+}
+
+func TestHistogramStatisticsKeepSampleEstimates(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.EnableHistograms(3, true)
+	for i := 1; i <= 20; i++ {
+		metrics.AddRequestTime(time.Duration(i)*time.Millisecond, true)
+	}
+	seed := int64(1)
+	UpdateMetricsStatistics(metrics, &StatisticsConfig{
+		Mean:      true,
+		Bootstrap: &BootstrapConfig{Enabled: true, Level: 0.95, Resamples: 200, Seed: &seed},
+		Outliers:  &OutlierConfig{Enabled: true, Method: "iqr", Threshold: 1.5, TrimPercent: 5},
+	})
+
+	stat := metrics.RequestMetrics.Statistics
+	if stat.ConfidenceIntervals["mean"] == nil || stat.Robust == nil {
+		t.Errorf("expected confidence intervals and robust statistics from the kept samples, got %+v", stat)
+	}
+}
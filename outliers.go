@@ -0,0 +1,132 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/montanaflynn/stats"
+)
+
+// madScale converts a median absolute deviation into a standard deviation
+// estimate for normally distributed data
+const madScale = 1.4826
+
+// RobustStatistics holds outlier detection results and statistics that are
+// insensitive to a few extreme values such as timeouts or GC pauses
+type RobustStatistics struct {
+	Method       string    `json:"method"`
+	Threshold    float64   `json:"threshold"`
+	LowerFence   float64   `json:"lower_fence"`
+	UpperFence   float64   `json:"upper_fence"`
+	OutlierCount int       `json:"outlier_count"`
+	Outliers     []float64 `json:"outliers,omitempty"`
+
+	// Mean and standard deviation of the values inside the fences
+	MeanWithoutOutliers   float64 `json:"mean_without_outliers"`
+	StdDevWithoutOutliers float64 `json:"std_dev_without_outliers"`
+
+	// TrimPercent is the share cut (trimmed) or clamped (winsorized) from each tail
+	TrimPercent    float64 `json:"trim_percent"`
+	TrimmedMean    float64 `json:"trimmed_mean"`
+	WinsorizedMean float64 `json:"winsorized_mean"`
+
+	// MAD is the median absolute deviation; RobustStdDev is MAD scaled to be
+	// comparable with a standard deviation
+	MAD          float64 `json:"mad"`
+	RobustStdDev float64 `json:"robust_std_dev"`
+	IQR          float64 `json:"iqr"`
+}
+
+// CalculateRobustStatistics detects outliers in values using the configured
+// method and computes trimmed, winsorized and MAD-based statistics
+func CalculateRobustStatistics(values []float64, config *OutlierConfig) *RobustStatistics {
+	if len(values) == 0 {
+		return nil
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	median, _ := stats.Median(sorted)
+	q1, _ := stats.Percentile(sorted, 25)
+	q3, _ := stats.Percentile(sorted, 75)
+	mad := medianAbsoluteDeviation(sorted, median)
+
+	robust := &RobustStatistics{
+		Method:       config.Method,
+		Threshold:    config.Threshold,
+		TrimPercent:  config.TrimPercent,
+		MAD:          mad,
+		RobustStdDev: madScale * mad,
+		IQR:          q3 - q1,
+	}
+
+	switch config.Method {
+	case "mad":
+		// Modified z-score |0.6745 (x - median) / MAD| above threshold. With a
+		// zero MAD every value but the median would qualify, so nothing is flagged.
+		if mad > 0 {
+			spread := config.Threshold * mad / 0.6745
+			robust.LowerFence = median - spread
+			robust.UpperFence = median + spread
+		} else {
+			robust.LowerFence = sorted[0]
+			robust.UpperFence = sorted[len(sorted)-1]
+		}
+	default:
+		robust.LowerFence = q1 - config.Threshold*robust.IQR
+		robust.UpperFence = q3 + config.Threshold*robust.IQR
+	}
+
+	inliers := make([]float64, 0, len(sorted))
+	for _, v := range sorted {
+		if v < robust.LowerFence || v > robust.UpperFence {
+			robust.Outliers = append(robust.Outliers, v)
+			continue
+		}
+		inliers = append(inliers, v)
+	}
+	robust.OutlierCount = len(robust.Outliers)
+	if len(inliers) > 0 {
+		robust.MeanWithoutOutliers, _ = stats.Mean(inliers)
+		robust.StdDevWithoutOutliers, _ = stats.StandardDeviation(inliers)
+	}
+
+	robust.TrimmedMean, robust.WinsorizedMean = trimmedMeans(sorted, config.TrimPercent)
+	return robust
+}
+
+// medianAbsoluteDeviation returns the median of the absolute deviations from median
+func medianAbsoluteDeviation(values []float64, median float64) float64 {
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - median)
+	}
+	mad, _ := stats.Median(deviations)
+	return mad
+}
+
+// trimmedMeans returns the mean of sorted with percent of the values cut from
+// each tail, and the mean with those values clamped to the nearest kept value
+func trimmedMeans(sorted []float64, percent float64) (float64, float64) {
+	cut := int(float64(len(sorted)) * percent / 100)
+	if 2*cut >= len(sorted) {
+		cut = (len(sorted) - 1) / 2
+	}
+
+	kept := sorted[cut : len(sorted)-cut]
+	trimmed, _ := stats.Mean(kept)
+
+	var sum float64
+	for i, v := range sorted {
+		switch {
+		case i < cut:
+			v = kept[0]
+		case i >= len(sorted)-cut:
+			v = kept[len(kept)-1]
+		}
+		sum += v
+	}
+	return trimmed, sum / float64(len(sorted))
+}
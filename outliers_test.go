@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRobustStatisticsIQR(t *testing.T) {
+	values := []float64{100, 102, 98, 101, 99, 103, 97, 100, 30000, 100}
+	robust := CalculateRobustStatistics(values, &OutlierConfig{Enabled: true, Method: "iqr", Threshold: 1.5, TrimPercent: 10})
+
+	if robust.OutlierCount != 1 || robust.Outliers[0] != 30000 {
+		t.Fatalf("expected the 30000 timeout as only outlier, got %v", robust.Outliers)
+	}
+	if robust.MeanWithoutOutliers < 99 || robust.MeanWithoutOutliers > 101 {
+		t.Errorf("expected mean without outliers near 100, got %v", robust.MeanWithoutOutliers)
+	}
+	// 10% trimming cuts 97 and 30000
+	if robust.TrimmedMean != 100.375 {
+		t.Errorf("expected trimmed mean 100.375, got %v", robust.TrimmedMean)
+	}
+	// 10% winsorizing clamps 97 to 98 and 30000 to 103
+	if robust.WinsorizedMean != 100.4 {
+		t.Errorf("expected winsorized mean 100.4, got %v", robust.WinsorizedMean)
+	}
+}
+
+func TestRobustStatisticsMAD(t *testing.T) {
+	values := []float64{10, 11, 12, 13, 14, 100}
+	robust := CalculateRobustStatistics(values, &OutlierConfig{Enabled: true, Method: "mad", Threshold: 3.5})
+
+	// Median 12.5, absolute deviations 2.5 1.5 0.5 0.5 1.5 87.5 -> MAD 1.5
+	if robust.MAD != 1.5 {
+		t.Errorf("expected MAD 1.5, got %v", robust.MAD)
+	}
+	if math.Abs(robust.RobustStdDev-1.5*madScale) > 1e-9 {
+		t.Errorf("expected robust std dev %v, got %v", 1.5*madScale, robust.RobustStdDev)
+	}
+	if robust.OutlierCount != 1 || robust.Outliers[0] != 100 {
+		t.Errorf("expected 100 as only outlier, got %v", robust.Outliers)
+	}
+}
+
+func TestRobustStatisticsZeroMAD(t *testing.T) {
+	values := []float64{5, 5, 5, 5, 9}
+	robust := CalculateRobustStatistics(values, &OutlierConfig{Enabled: true, Method: "mad", Threshold: 3.5})
+	if robust.OutlierCount != 0 {
+		t.Errorf("expected no outliers with zero MAD, got %v", robust.Outliers)
+	}
+}
+
+func TestCalculateStatisticsWithOutliers(t *testing.T) {
+	config := &StatisticsConfig{
+		Mean:     true,
+		Outliers: &OutlierConfig{Enabled: true, Method: "iqr", Threshold: 1.5, TrimPercent: 5},
+	}
+	stat := CalculateStatistics([]int64{1000, 1100, 900, 1000, 60000000}, config)
+	if stat.Robust == nil || stat.Robust.OutlierCount != 1 {
+		t.Fatalf("expected one outlier, got %+v", stat.Robust)
+	}
+
+	scaled := scaleStatistics(stat, "ms")
+	if scaled.Robust.Outliers[0] != 60000 {
+		t.Errorf("expected outlier scaled to 60000ms, got %v", scaled.Robust.Outliers[0])
+	}
+}
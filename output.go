@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// outputFiles maps each report output to its default base name and extension
+var outputFiles = map[string]struct{ name, ext string }{
+	"json":        {"result", ".json"},
+	"short":       {"results_short", ".json"},
+	"junit":       {"junit", ".xml"},
+	"html":        {"report", ".html"},
+	"csv":         {"results", ".csv"},
+	"samples_csv": {"samples", ".csv"},
+	"markdown":    {"results", ".md"},
+}
+
+// newRunID returns a sortable, practically unique run identifier
+func newRunID(started time.Time) string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return started.UTC().Format("20060102-150405")
+	}
+	return started.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// expandTemplate replaces the {name}, {run_id}, {timestamp} and {date}
+// placeholders of a directory or file name template; the time placeholders
+// take the time the run started
+func expandTemplate(template, name, runID string, started time.Time) string {
+	return strings.NewReplacer(
+		"{name}", name,
+		"{run_id}", runID,
+		"{timestamp}", started.UTC().Format("20060102T150405Z"),
+		"{date}", started.UTC().Format("2006-01-02"),
+	).Replace(template)
+}
+
+// validateOutputConfig checks the file name template can tell outputs apart
+func validateOutputConfig(output *OutputConfig) error {
+	if !strings.Contains(output.NameTemplate, "{name}") {
+		return fmt.Errorf("name_template %q must contain {name}", output.NameTemplate)
+	}
+	if strings.ContainsAny(output.NameTemplate, `/\`) {
+		return fmt.Errorf("name_template %q must not contain path separators; use dir", output.NameTemplate)
+	}
+	return nil
+}
+
+// Path returns where a report output is written for a run started at started,
+// creating the output directory if needed
+func (o *OutputConfig) Path(output string, started time.Time) (string, error) {
+	file, ok := outputFiles[output]
+	if !ok {
+		return "", fmt.Errorf("unsupported output %q", output)
+	}
+
+	dir := expandTemplate(o.Dir, file.name, o.RunID, started)
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create output directory %s: %w", dir, err)
+		}
+	}
+	return filepath.Join(dir, expandTemplate(o.NameTemplate, file.name, o.RunID, started)+file.ext), nil
+}
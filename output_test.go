@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestOutputPath(t *testing.T) {
+	base := t.TempDir()
+	output := &OutputConfig{
+		Dir:          filepath.Join(base, "runs", "{date}", "{run_id}"),
+		NameTemplate: "{timestamp}_{name}",
+		RunID:        "weekly-42",
+	}
+	started := time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+
+	path, err := output.Path("json", started)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := filepath.Join(base, "runs", "2026-03-14", "weekly-42", "20260314T092653Z_result.json")
+	if path != want {
+		t.Errorf("expected %s, got %s", want, path)
+	}
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		t.Errorf("expected output directory to be created: %v", err)
+	}
+
+	short, _ := output.Path("short", started)
+	if filepath.Base(short) != "20260314T092653Z_results_short.json" {
+		t.Errorf("unexpected short summary name %s", filepath.Base(short))
+	}
+}
+
+func TestOutputPathDefaults(t *testing.T) {
+	output := &OutputConfig{NameTemplate: "{name}"}
+	for name, want := range map[string]string{"json": "result.json", "junit": "junit.xml", "markdown": "results.md"} {
+		if path, _ := output.Path(name, time.Now()); path != want {
+			t.Errorf("%s: expected %s, got %s", name, want, path)
+		}
+	}
+	if _, err := output.Path("pdf", time.Now()); err == nil {
+		t.Error("expected error for unknown output")
+	}
+}
+
+func TestValidateOutputConfig(t *testing.T) {
+	if err := validateOutputConfig(&OutputConfig{NameTemplate: "{run_id}"}); err == nil {
+		t.Error("expected error for template without {name}")
+	}
+	if err := validateOutputConfig(&OutputConfig{NameTemplate: "runs/{name}"}); err == nil {
+		t.Error("expected error for template with a path separator")
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	started := time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+	id := newRunID(started)
+	if !regexp.MustCompile(`^20260314-092653-[0-9a-f]{6}$`).MatchString(id) {
+		t.Errorf("unexpected run ID format %q", id)
+	}
+	if id == newRunID(started) {
+		t.Error("expected run IDs of the same second to differ")
+	}
+}
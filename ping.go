@@ -38,4 +38,58 @@ func (p *PingClient) PingProxy(ctx context.Context, proxy *Proxy) (time.Duration
 	conn.Close()
 	
 	return duration, nil
+}
+
+// ConnectError reports that the TCP connection to a proxy could not be established
+type ConnectError struct {
+	Address string
+	Err     error
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("failed to connect to proxy %s: %v", e.Address, e.Err)
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+// PingHandshake connects to the proxy and performs a protocol-level handshake:
+// the SOCKS5 greeting and authentication for SOCKS proxies, or an HTTP CONNECT to
+// connectTarget for HTTP proxies. It returns the TCP connect time and the time the
+// handshake itself took on the established connection. If the connection fails
+// the error is a *ConnectError; other errors mean only the handshake failed.
+func (p *PingClient) PingHandshake(ctx context.Context, proxy *Proxy, connectTarget string) (time.Duration, time.Duration, error) {
+	address := proxy.Address()
+
+	dialer := &net.Dialer{
+		Timeout: p.timeout,
+	}
+
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return 0, 0, &ConnectError{Address: address, Err: err}
+	}
+	connect := time.Since(start)
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	handshakeStart := time.Now()
+	switch proxy.Protocol {
+	case "socks":
+		err = socks5Handshake(conn, proxy.Username, proxy.Password)
+	case "http", "https":
+		_, err = NewTunnelDialer(proxy, p.timeout).writeConnect(conn, connectTarget)
+	default:
+		err = fmt.Errorf("unsupported protocol for handshake ping: %s", proxy.Protocol)
+	}
+	if err != nil {
+		return connect, 0, err
+	}
+
+	return connect, time.Since(handshakeStart), nil
 }
\ No newline at end of file
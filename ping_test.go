@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPingHandshakeSOCKS5(t *testing.T) {
+	proxy := startSOCKS5Server(t, "user", "pass")
+	client := NewPingClient(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connect, handshake, err := client.PingHandshake(ctx, proxy, "")
+	if err != nil {
+		t.Fatalf("handshake ping failed: %v", err)
+	}
+	if connect <= 0 || handshake <= 0 {
+		t.Errorf("expected positive timings, got connect=%v handshake=%v", connect, handshake)
+	}
+}
+
+func TestPingHandshakeSOCKS5BadCredentials(t *testing.T) {
+	proxy := startSOCKS5Server(t, "user", "pass")
+	proxy.Password = "wrong"
+	client := NewPingClient(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	connect, _, err := client.PingHandshake(ctx, proxy, "")
+	if err == nil {
+		t.Fatal("expected authentication failure")
+	}
+	if connect <= 0 {
+		t.Errorf("expected TCP connect time to be reported on auth failure, got %v", connect)
+	}
+}
+
+func TestPingHandshakeHTTPConnect(t *testing.T) {
+	echoAddr := startEchoServer(t)
+	proxy := startConnectProxy(t, "Basic dXNlcjpwYXNz")
+	client := NewPingClient(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, _, err := client.PingHandshake(ctx, proxy, echoAddr); err != nil {
+		t.Fatalf("CONNECT ping failed: %v", err)
+	}
+}
+
+func TestPingHandshakeConnectError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+	client := NewPingClient(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, _, err = client.PingHandshake(ctx, &Proxy{Protocol: "socks", Host: host, Port: port}, "")
+	var connectErr *ConnectError
+	if !errors.As(err, &connectErr) {
+		t.Fatalf("expected a ConnectError, got %v", err)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) || classifyError(err) != ErrorClassConnectionRefused {
+		t.Errorf("expected the dial error to stay classifiable, got %v", err)
+	}
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// prometheusBuckets are the latency histogram upper bounds in seconds
+var prometheusBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// prometheusQuantiles are the percentiles exported as last-run latency gauges
+var prometheusQuantiles = []float64{50, 90, 95, 99}
+
+// PrometheusExporter renders benchmark metrics in the Prometheus text
+// exposition format. Counters and histograms are read live from the engine's
+// metrics; last-run gauges are added once the report has been generated.
+type PrometheusExporter struct {
+	namespace string
+	source    func() map[string]*Metrics
+	mu        sync.Mutex
+	result    *BenchmarkResult
+}
+
+// NewPrometheusExporter creates an exporter reading metrics from source
+func NewPrometheusExporter(config *PrometheusConfig, source func() map[string]*Metrics) *PrometheusExporter {
+	return &PrometheusExporter{
+		namespace: config.Namespace,
+		source:    source,
+	}
+}
+
+// SetResult sets the finished run whose summary is exported as last-run gauges
+func (e *PrometheusExporter) SetResult(result *BenchmarkResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.result = result
+}
+
+// Serve starts serving /metrics on addr in the background and returns the
+// server so the caller can shut it down
+func (e *PrometheusExporter) Serve(addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &http.Server{Handler: e.Handler()}
+	go server.Serve(listener)
+	return server, nil
+}
+
+// Handler returns an HTTP handler serving the metrics on /metrics
+func (e *PrometheusExporter) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, req *http.Request) {
+		var buf bytes.Buffer
+		if err := e.Write(&buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+	return mux
+}
+
+// WriteTextfile writes the metrics to path for the node_exporter textfile
+// collector. The file is written to a temporary name and renamed so the
+// collector never reads a partial file.
+func (e *PrometheusExporter) WriteTextfile(path string) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if err := e.Write(temp); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Chmod(0644); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Write renders all metrics in the text exposition format
+func (e *PrometheusExporter) Write(w io.Writer) error {
+	snapshots := make([]*MetricsSnapshot, 0)
+	for _, m := range e.source() {
+		snapshots = append(snapshots, m.Snapshot())
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return proxyID(snapshots[i].ProxyString) < proxyID(snapshots[j].ProxyString)
+	})
+
+	buf := bufio.NewWriter(w)
+	e.writeCounters(buf, snapshots)
+	e.writeHistograms(buf, snapshots)
+
+	e.mu.Lock()
+	result := e.result
+	e.mu.Unlock()
+	if result != nil {
+		e.writeLastRun(buf, result)
+	}
+	return buf.Flush()
+}
+
+// writeCounters writes request and ping outcome counters
+func (e *PrometheusExporter) writeCounters(w io.Writer, snapshots []*MetricsSnapshot) {
+	name := e.name("requests_total")
+	writeHeader(w, name, "counter", "Requests sent through the proxy by result.")
+	for _, s := range snapshots {
+		labels := proxyLabels(s.ProxyString)
+		writeSample(w, name, withLabel(labels, "result", "success"), float64(s.RequestsSuccessful))
+		writeSample(w, name, withLabel(labels, "result", "failure"), float64(s.RequestsFailed))
+	}
+
+	name = e.name("request_failures_total")
+	writeHeader(w, name, "counter", "Failed requests by error class.")
+	for _, s := range snapshots {
+		labels := proxyLabels(s.ProxyString)
+		for _, class := range sortedKeys(s.RequestFailuresByClass) {
+			writeSample(w, name, withLabel(labels, "class", class), float64(s.RequestFailuresByClass[class]))
+		}
+	}
+
+	name = e.name("response_bytes_total")
+	writeHeader(w, name, "counter", "Response body bytes received through the proxy.")
+	for _, s := range snapshots {
+		writeSample(w, name, proxyLabels(s.ProxyString), float64(s.ResponseBytes))
+	}
+
+	name = e.name("pings_total")
+	writeHeader(w, name, "counter", "Ping attempts to the proxy by result.")
+	for _, s := range snapshots {
+		labels := proxyLabels(s.ProxyString)
+		writeSample(w, name, withLabel(labels, "result", "success"), float64(s.PingSuccessful))
+		writeSample(w, name, withLabel(labels, "result", "failure"), float64(s.PingFailed))
+	}
+
+	name = e.name("ping_failures_total")
+	writeHeader(w, name, "counter", "Failed pings by error class.")
+	for _, s := range snapshots {
+		labels := proxyLabels(s.ProxyString)
+		for _, class := range sortedKeys(s.PingFailuresByClass) {
+			writeSample(w, name, withLabel(labels, "class", class), float64(s.PingFailuresByClass[class]))
+		}
+	}
+}
+
+// writeHistograms writes the request, ping and derived latency histograms
+func (e *PrometheusExporter) writeHistograms(w io.Writer, snapshots []*MetricsSnapshot) {
+	histograms := []struct {
+		name         string
+		help         string
+		distribution func(*MetricsSnapshot) *Histogram
+	}{
+		{"request_duration_seconds", "Successful request latency through the proxy.",
+			func(s *MetricsSnapshot) *Histogram { return s.RequestLatency }},
+		{"ping_duration_seconds", "Ping latency to the proxy.",
+			func(s *MetricsSnapshot) *Histogram { return s.PingLatency }},
+		{"derived_duration_seconds", "Derived proxy processing time (request time minus twice the ping).",
+			func(s *MetricsSnapshot) *Histogram { return s.DerivedLatency }},
+	}
+
+	for _, h := range histograms {
+		name := e.name(h.name)
+		writeHeader(w, name, "histogram", h.help)
+		for _, s := range snapshots {
+			writeHistogram(w, name, proxyLabels(s.ProxyString), h.distribution(s))
+		}
+	}
+}
+
+// writeLastRun writes gauges summarizing the finished run
+func (e *PrometheusExporter) writeLastRun(w io.Writer, result *BenchmarkResult) {
+	seconds := func(v float64) float64 {
+		return fromMicros(toMicros(v, result.Unit), "s")
+	}
+
+	name := e.name("last_run_timestamp_seconds")
+	writeHeader(w, name, "gauge", "Unix time the last run finished.")
+	writeSample(w, name, [][2]string{{"run_id", result.RunID}}, float64(result.Timestamp.Unix()))
+
+	name = e.name("last_run_success_ratio")
+	writeHeader(w, name, "gauge", "Request success ratio of the last run.")
+	for _, proxy := range sortedProxies(result) {
+		request := proxy.RequestMetrics
+		if request.Total == 0 {
+			continue
+		}
+		writeSample(w, name, proxyLabels(proxy.ProxyString), float64(request.Successful)/float64(request.Total))
+	}
+
+	name = e.name("last_run_request_latency_seconds")
+	writeHeader(w, name, "gauge", "Successful request latency percentiles of the last run.")
+	for _, proxy := range sortedProxies(result) {
+		stat := proxy.RequestMetrics.Statistics
+		if stat == nil {
+			continue
+		}
+		labels := proxyLabels(proxy.ProxyString)
+		for _, q := range prometheusQuantiles {
+			value, ok := stat.Percentiles[fmt.Sprintf("%.1f", q)]
+			if !ok {
+				continue
+			}
+			writeSample(w, name, withLabel(labels, "quantile", formatFloat(q/100)), seconds(value))
+		}
+	}
+
+	name = e.name("last_run_ping_jitter_seconds")
+	writeHeader(w, name, "gauge", "Ping jitter of the last run.")
+	for _, proxy := range sortedProxies(result) {
+		if proxy.PingMetrics.Attempts == 0 {
+			continue
+		}
+		writeSample(w, name, proxyLabels(proxy.ProxyString), seconds(proxy.PingMetrics.Jitter))
+	}
+
+	if len(result.Ranking) > 0 {
+		name = e.name("last_run_score")
+		writeHeader(w, name, "gauge", "Proxy score (0-100) of the last run.")
+		for _, score := range result.Ranking {
+			writeSample(w, name, proxyLabels(score.Proxy), score.Score)
+		}
+	}
+
+	if result.Gate != nil {
+		name = e.name("last_run_gate_passed")
+		writeHeader(w, name, "gauge", "Whether the last run passed all threshold rules (1) or not (0).")
+		passed := 0.0
+		if result.Gate.Passed {
+			passed = 1
+		}
+		writeSample(w, name, nil, passed)
+	}
+}
+
+// name prefixes a metric name with the namespace
+func (e *PrometheusExporter) name(metric string) string {
+	if e.namespace == "" {
+		return metric
+	}
+	return e.namespace + "_" + metric
+}
+
+// proxyLabels returns the identifying labels of a proxy, never its credentials
+func proxyLabels(proxyString string) [][2]string {
+	protocol := ""
+	if proxy, err := ParseProxy(proxyString); err == nil {
+		protocol = proxy.Protocol
+	}
+	return [][2]string{{"proxy", proxyID(proxyString)}, {"protocol", protocol}}
+}
+
+// withLabel returns labels with one more label appended
+func withLabel(labels [][2]string, name, value string) [][2]string {
+	extended := make([][2]string, len(labels), len(labels)+1)
+	copy(extended, labels)
+	return append(extended, [2]string{name, value})
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric family
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+// writeSample writes a single sample line
+func writeSample(w io.Writer, name string, labels [][2]string, value float64) {
+	fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(labels), formatFloat(value))
+}
+
+// writeHistogram writes the cumulative buckets, sum and count of a microsecond
+// histogram in seconds
+func writeHistogram(w io.Writer, name string, labels [][2]string, h *Histogram) {
+	indexes := h.sortedIndexes()
+	var cumulative int64
+	next := 0
+	for _, le := range prometheusBuckets {
+		bound := int64(le * 1e6)
+		for next < len(indexes) && h.clamp(h.bucketMidpoint(indexes[next])) <= bound {
+			cumulative += h.counts[indexes[next]]
+			next++
+		}
+		writeSample(w, name+"_bucket", withLabel(labels, "le", formatFloat(le)), float64(cumulative))
+	}
+	writeSample(w, name+"_bucket", withLabel(labels, "le", "+Inf"), float64(h.Count()))
+	writeSample(w, name+"_sum", labels, h.sum/1e6)
+	writeSample(w, name+"_count", labels, float64(h.Count()))
+}
+
+// formatLabels renders a label set, escaping values as the format requires
+func formatLabels(labels [][2]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(label[0])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(label[1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelEscaper escapes backslashes, quotes and newlines in label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// sortedKeys returns the keys of a count map in ascending order
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusCountersAndHistograms(t *testing.T) {
+	m := NewMetrics("socks:a.example:1080:user:secret:enabled")
+	m.AddRequestTime(2*time.Millisecond, true)
+	m.AddRequestTime(200*time.Millisecond, true)
+	m.AddRequestFailure(time.Second, errors.New("connection refused"))
+	metrics := map[string]*Metrics{m.ProxyString: m}
+
+	exporter := NewPrometheusExporter(&PrometheusConfig{Namespace: "pb"}, func() map[string]*Metrics { return metrics })
+	var out strings.Builder
+	if err := exporter.Write(&out); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	text := out.String()
+
+	if strings.Contains(text, "secret") || strings.Contains(text, "user") {
+		t.Fatalf("credentials leaked into metrics:\n%s", text)
+	}
+	labels := `proxy="socks://a.example:1080",protocol="socks"`
+	for _, line := range []string{
+		`# TYPE pb_requests_total counter`,
+		`pb_requests_total{` + labels + `,result="success"} 2`,
+		`pb_requests_total{` + labels + `,result="failure"} 1`,
+		`# TYPE pb_request_duration_seconds histogram`,
+		`pb_request_duration_seconds_bucket{` + labels + `,le="0.001"} 0`,
+		`pb_request_duration_seconds_bucket{` + labels + `,le="0.0025"} 1`,
+		`pb_request_duration_seconds_bucket{` + labels + `,le="0.25"} 2`,
+		`pb_request_duration_seconds_bucket{` + labels + `,le="+Inf"} 2`,
+		`pb_request_duration_seconds_sum{` + labels + `} 0.202`,
+		`pb_request_duration_seconds_count{` + labels + `} 2`,
+	} {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("expected line %q in:\n%s", line, text)
+		}
+	}
+	if strings.Contains(text, "pb_last_run_") {
+		t.Error("last-run gauges must only be written once a result is set")
+	}
+}
+
+func TestPrometheusLastRunGauges(t *testing.T) {
+	result := tabularResult(t)
+	result.RunID = "run-1"
+	exporter := NewPrometheusExporter(&PrometheusConfig{Namespace: "pb"}, func() map[string]*Metrics { return nil })
+	exporter.SetResult(result)
+
+	var out strings.Builder
+	if err := exporter.Write(&out); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	text := out.String()
+
+	for _, line := range []string{
+		`pb_last_run_timestamp_seconds{run_id="run-1"}`,
+		`pb_last_run_success_ratio{proxy="http://a.example:8080",protocol="http"} 0.5`,
+		`pb_last_run_request_latency_seconds{proxy="http://a.example:8080",protocol="http",quantile="0.5"} 0.5`,
+		`pb_last_run_score{proxy="socks://b.example:1080",protocol="socks"} 100`,
+	} {
+		if !strings.Contains(text, line) {
+			t.Errorf("expected %q in:\n%s", line, text)
+		}
+	}
+}
+
+func TestPrometheusLabelEscaping(t *testing.T) {
+	got := formatLabels([][2]string{{"class", "a\"b\\c\nd"}})
+	want := `{class="a\"b\\c\nd"}`
+	if got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestPrometheusTextfile(t *testing.T) {
+	m := NewMetrics("http:a.example:8080:::enabled")
+	m.AddRequestTime(10*time.Millisecond, true)
+	metrics := map[string]*Metrics{m.ProxyString: m}
+	exporter := NewPrometheusExporter(&PrometheusConfig{Namespace: "pb"}, func() map[string]*Metrics { return metrics })
+
+	path := filepath.Join(t.TempDir(), "collector", "proxy_benchmark.prom")
+	if err := exporter.WriteTextfile(path); err != nil {
+		t.Fatalf("failed to write textfile: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "pb_requests_total") {
+		t.Errorf("textfile is missing metrics:\n%s", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected only the textfile in the collector directory, got %d entries", len(entries))
+	}
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	m := NewMetrics("http:a.example:8080:::enabled")
+	m.AddRequestTime(10*time.Millisecond, true)
+	metrics := map[string]*Metrics{m.ProxyString: m}
+	exporter := NewPrometheusExporter(&PrometheusConfig{}, func() map[string]*Metrics { return metrics })
+
+	server := httptest.NewServer(exporter.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), `requests_total{proxy="http://a.example:8080",protocol="http",result="success"} 1`) {
+		t.Errorf("unexpected metrics:\n%s", body)
+	}
+}
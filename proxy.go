@@ -46,3 +46,30 @@ func (p *Proxy) Address() string {
 func (p *Proxy) String() string {
 	return fmt.Sprintf("%s:%s:%s:%s:%s:%s", p.Protocol, p.Host, p.Port, p.Username, p.Password, p.Status)
 }
+
+// ID returns a credential-free identifier of the form protocol://host:port
+func (p *Proxy) ID() string {
+	return fmt.Sprintf("%s://%s", p.Protocol, p.Address())
+}
+
+// proxyID returns the ID of a proxy string, or the redacted string if it cannot be parsed
+func proxyID(proxyString string) string {
+	proxy, err := ParseProxy(proxyString)
+	if err != nil {
+		return redactProxy(proxyString)
+	}
+	return proxy.ID()
+}
+
+// matchesProxy reports whether key identifies the proxy, either as its ID
+// (protocol://host:port), as host:port or as the full proxy string
+func matchesProxy(proxyString, key string) bool {
+	if key == proxyString {
+		return true
+	}
+	proxy, err := ParseProxy(proxyString)
+	if err != nil {
+		return false
+	}
+	return key == proxy.ID() || key == proxy.Address()
+}
@@ -2,72 +2,263 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
-// BenchmarkResult represents the complete benchmark result
+// BenchmarkResult represents the complete benchmark result. Statistics are
+// expressed in Unit; raw samples and histograms stay in microseconds.
 type BenchmarkResult struct {
-	Timestamp time.Time       `json:"timestamp"`
-	Proxies   []*ProxyMetrics `json:"proxies"`
+	SchemaVersion int               `json:"schema_version"`
+	RunID         string            `json:"run_id,omitempty"`
+	Timestamp     time.Time         `json:"timestamp"`
+	Unit          string            `json:"unit"`
+	Proxies       []*ProxyMetrics   `json:"proxies"`
+	Aggregate     *AggregateMetrics `json:"aggregate,omitempty"`
+	// Comparisons holds pairwise significance tests for request and derived times
+	Comparisons []*ProxyComparison `json:"comparisons,omitempty"`
+	// Ranking orders proxies by composite score, best first
+	Ranking []*ProxyScore `json:"ranking,omitempty"`
+	// Gate holds the outcome of the threshold rules, if any are configured
+	Gate *GateResult `json:"gate,omitempty"`
+	// Metadata records the environment and effective configuration of the run
+	Metadata *RunMetadata `json:"metadata,omitempty"`
 }
 
-// ShortSummary represents a concise summary with only mean delivered per proxy
+// AggregateMetrics holds histograms merged across all proxies
+type AggregateMetrics struct {
+	RequestHistogram  *Histogram  `json:"request_histogram"`
+	RequestStatistics *Statistics `json:"request_statistics,omitempty"`
+	PingHistogram     *Histogram  `json:"ping_histogram"`
+	PingStatistics    *Statistics `json:"ping_statistics,omitempty"`
+	DerivedHistogram  *Histogram  `json:"derived_histogram"`
+	DerivedStatistics *Statistics `json:"derived_statistics,omitempty"`
+}
+
+// ShortSummary represents a concise summary with the mean delivered per proxy
+// and the composite score ranking
 type ShortSummary struct {
-	Timestamp time.Time                    `json:"timestamp"`
-	Proxies   map[string]float64          `json:"proxies"`
+	RunID     string             `json:"run_id,omitempty"`
+	Timestamp time.Time          `json:"timestamp"`
+	Unit      string             `json:"unit"`
+	Proxies   map[string]float64 `json:"proxies"`
+	Ranking   []*ProxyScore      `json:"ranking,omitempty"`
 }
 
 // ProxyMetrics represents metrics for a single proxy
 type ProxyMetrics struct {
-	ProxyString    string         `json:"proxy"`
-	RequestMetrics RequestMetrics `json:"request_metrics"`
-	PingMetrics    PingMetrics    `json:"ping_metrics"`
-	DerivedMetrics DerivedMetrics `json:"derived_metrics"`
+	ProxyString      string                   `json:"proxy"`
+	RequestMetrics   RequestMetrics           `json:"request_metrics"`
+	PingMetrics      PingMetrics              `json:"ping_metrics"`
+	DerivedMetrics   DerivedMetrics           `json:"derived_metrics"`
+	TunnelMetrics    *TunnelMetrics           `json:"tunnel_metrics,omitempty"`
+	UDPMetrics       *UDPMetrics              `json:"udp_metrics,omitempty"`
+	WebSocketMetrics *WebSocketMetrics        `json:"websocket_metrics,omitempty"`
+	StabilityMetrics *StabilityMetrics        `json:"stability_metrics,omitempty"`
+	TimeSeries       []*TimeBucket            `json:"time_series,omitempty"`
+	Validation       []*ValidationCheckResult `json:"validation,omitempty"`
+	SLOs             []*SLOResult             `json:"slos,omitempty"`
+	Apdex            *ApdexResult             `json:"apdex,omitempty"`
+}
+
+// reportOutputs lists the supported report outputs
+var reportOutputs = []string{"json", "short", "junit", "html", "csv", "samples_csv", "markdown"}
+
+// validateOutputs checks that every configured output is supported
+func validateOutputs(outputs []string) error {
+	for _, output := range outputs {
+		if _, ok := outputFiles[output]; !ok {
+			return fmt.Errorf("unsupported output %q (expected one of %s)", output, strings.Join(reportOutputs, ", "))
+		}
+	}
+	return nil
 }
 
 // Reporter generates benchmark reports
-type Reporter struct{}
+type Reporter struct {
+	config *Config
+}
 
 // NewReporter creates a new reporter
-func NewReporter() *Reporter {
-	return &Reporter{}
+func NewReporter(config *Config) *Reporter {
+	return &Reporter{
+		config: config,
+	}
 }
 
 // GenerateReport generates a benchmark report from metrics
 func (r *Reporter) GenerateReport(metrics map[string]*Metrics) *BenchmarkResult {
 	result := &BenchmarkResult{
-		Timestamp: time.Now(),
-		Proxies:   make([]*ProxyMetrics, 0),
+		SchemaVersion: resultSchemaVersion,
+		RunID:         r.config.Output.RunID,
+		Timestamp:     time.Now(),
+		Unit:          r.unit(),
+		Proxies:       make([]*ProxyMetrics, 0),
 	}
 
 	for _, m := range metrics {
-		proxyMetrics := &ProxyMetrics{
-			ProxyString:    m.ProxyString,
-			RequestMetrics: m.RequestMetrics,
-			PingMetrics:    m.PingMetrics,
-			DerivedMetrics: m.DerivedMetrics,
-		}
+		proxyMetrics := r.displayMetrics(m)
+		proxyMetrics.SLOs = r.evaluateSLOs(m)
+		proxyMetrics.Apdex = r.calculateApdex(m)
 		result.Proxies = append(result.Proxies, proxyMetrics)
 	}
 
+	result.Aggregate = r.aggregateHistograms(metrics)
+	result.Comparisons = r.compareProxies(metrics)
+	result.Ranking = r.rankProxies(metrics)
+	result.Gate = r.evaluateGate(metrics)
+
 	return result
 }
 
+// aggregateHistograms merges the per-proxy histograms, if histograms are enabled
+func (r *Reporter) aggregateHistograms(metrics map[string]*Metrics) *AggregateMetrics {
+	histogram := r.config.Statistics.Histogram
+	if histogram == nil || !histogram.Enabled {
+		return nil
+	}
+
+	aggregate := &AggregateMetrics{
+		RequestHistogram: NewHistogram(histogram.SignificantFigures),
+		PingHistogram:    NewHistogram(histogram.SignificantFigures),
+		DerivedHistogram: NewHistogram(histogram.SignificantFigures),
+	}
+	for _, m := range metrics {
+		aggregate.RequestHistogram.Merge(m.RequestMetrics.Histogram)
+		aggregate.PingHistogram.Merge(m.PingMetrics.Histogram)
+		aggregate.DerivedHistogram.Merge(m.DerivedMetrics.Histogram)
+	}
+
+	aggregate.RequestStatistics = r.display(CalculateHistogramStatistics(aggregate.RequestHistogram, &r.config.Statistics))
+	aggregate.PingStatistics = r.display(CalculateHistogramStatistics(aggregate.PingHistogram, &r.config.Statistics))
+	aggregate.DerivedStatistics = r.display(CalculateHistogramStatistics(aggregate.DerivedHistogram, &r.config.Statistics))
+	return aggregate
+}
+
+// compareProxies runs pairwise Mann-Whitney U tests on request and derived times
+func (r *Reporter) compareProxies(metrics map[string]*Metrics) []*ProxyComparison {
+	significance := r.config.Report.Significance
+	if significance == nil || !significance.Enabled {
+		return nil
+	}
+
+	requestSamples := make(map[string][]float64)
+	derivedSamples := make(map[string][]float64)
+	for _, m := range metrics {
+		requestSamples[redactProxy(m.ProxyString)] = toFloat64s(m.GetRequestTimes())
+		derivedSamples[redactProxy(m.ProxyString)] = toFloat64s(m.GetDerivedTimes())
+	}
+
+	comparisons := make([]*ProxyComparison, 0, 2)
+	if comparison := compareSamples("request", requestSamples, significance.Alpha); comparison != nil {
+		comparisons = append(comparisons, comparison)
+	}
+	if comparison := compareSamples("derived", derivedSamples, significance.Alpha); comparison != nil {
+		comparisons = append(comparisons, comparison)
+	}
+	return comparisons
+}
+
+// toFloat64s converts samples to float64 values
+func toFloat64s(values []int64) []float64 {
+	floats := make([]float64, len(values))
+	for i, v := range values {
+		floats[i] = float64(v)
+	}
+	return floats
+}
+
+// displayMetrics copies a proxy's metrics with all statistics converted to the
+// display unit and the proxy password redacted
+func (r *Reporter) displayMetrics(m *Metrics) *ProxyMetrics {
+	proxyMetrics := &ProxyMetrics{
+		ProxyString:    redactProxy(m.ProxyString),
+		RequestMetrics: m.RequestMetrics,
+		PingMetrics:    m.PingMetrics,
+		DerivedMetrics: m.DerivedMetrics,
+		Validation:     m.Validation,
+	}
+
+	proxyMetrics.RequestMetrics.Statistics = r.display(m.RequestMetrics.Statistics)
+	proxyMetrics.RequestMetrics.FailedStatistics = r.display(m.RequestMetrics.FailedStatistics)
+	if m.RequestMetrics.FailuresByClass != nil {
+		proxyMetrics.RequestMetrics.FailuresByClass = make(map[string]*FailureMetrics, len(m.RequestMetrics.FailuresByClass))
+		for class, failures := range m.RequestMetrics.FailuresByClass {
+			displayed := *failures
+			displayed.Statistics = r.display(failures.Statistics)
+			proxyMetrics.RequestMetrics.FailuresByClass[class] = &displayed
+		}
+	}
+	proxyMetrics.PingMetrics.Statistics = r.display(m.PingMetrics.Statistics)
+	proxyMetrics.PingMetrics.HandshakeStatistics = r.display(m.PingMetrics.HandshakeStatistics)
+	proxyMetrics.PingMetrics.Jitter = fromMicros(m.PingMetrics.Jitter, r.unit())
+	proxyMetrics.DerivedMetrics.Statistics = r.display(m.DerivedMetrics.Statistics)
+
+	if m.TunnelMetrics != nil {
+		tunnel := *m.TunnelMetrics
+		tunnel.EstablishStatistics = r.display(tunnel.EstablishStatistics)
+		tunnel.EchoStatistics = r.display(tunnel.EchoStatistics)
+		proxyMetrics.TunnelMetrics = &tunnel
+	}
+	if m.UDPMetrics != nil {
+		udp := *m.UDPMetrics
+		udp.SetupStatistics = r.display(udp.SetupStatistics)
+		udp.RTTStatistics = r.display(udp.RTTStatistics)
+		proxyMetrics.UDPMetrics = &udp
+	}
+	if m.WebSocketMetrics != nil {
+		ws := *m.WebSocketMetrics
+		ws.HandshakeStatistics = r.display(ws.HandshakeStatistics)
+		ws.RTTStatistics = r.display(ws.RTTStatistics)
+		ws.TimeToDropStatistics = r.display(ws.TimeToDropStatistics)
+		proxyMetrics.WebSocketMetrics = &ws
+	}
+	if m.StabilityMetrics != nil {
+		stability := *m.StabilityMetrics
+		stability.TimeToDropStatistics = r.display(stability.TimeToDropStatistics)
+		proxyMetrics.StabilityMetrics = &stability
+	}
+	for _, bucket := range m.GetTimeSeries() {
+		displayed := *bucket
+		displayed.Statistics = r.display(bucket.Statistics)
+		proxyMetrics.TimeSeries = append(proxyMetrics.TimeSeries, &displayed)
+	}
+
+	return proxyMetrics
+}
+
+// unit returns the configured display unit, defaulting to milliseconds
+func (r *Reporter) unit() string {
+	if r.config.Report.Unit == "" {
+		return "ms"
+	}
+	return r.config.Report.Unit
+}
+
+// display converts microsecond statistics to the display unit
+func (r *Reporter) display(stat *Statistics) *Statistics {
+	return scaleStatistics(stat, r.unit())
+}
+
 // GenerateShortSummary generates a short summary with only mean delivered per proxy
 func (r *Reporter) GenerateShortSummary(metrics map[string]*Metrics) *ShortSummary {
 	summary := &ShortSummary{
+		RunID:     r.config.Output.RunID,
 		Timestamp: time.Now(),
+		Unit:      r.unit(),
 		Proxies:   make(map[string]float64),
 	}
 
 	for _, m := range metrics {
 		if m.DerivedMetrics.Statistics != nil {
-			summary.Proxies[m.ProxyString] = m.DerivedMetrics.Statistics.Mean
+			summary.Proxies[redactProxy(m.ProxyString)] = fromMicros(m.DerivedMetrics.Statistics.Mean, r.unit())
 		} else {
-			summary.Proxies[m.ProxyString] = 0.0
+			summary.Proxies[redactProxy(m.ProxyString)] = 0.0
 		}
 	}
+	summary.Ranking = r.rankProxies(metrics)
 
 	return summary
 }
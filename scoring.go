@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Scoring component names
+const (
+	ScoreSuccessRate = "success_rate"
+	ScoreP50         = "p50"
+	ScoreP95         = "p95"
+	ScoreJitter      = "jitter"
+	ScoreThroughput  = "throughput"
+	ScoreCost        = "cost"
+)
+
+// ProxyScore is a proxy's position in the ranking. Components hold each scoring
+// component normalized to 0-1 (higher is better); Score is their weighted
+// average scaled to 0-100, with every component other than the success rate
+// discounted by the success rate.
+type ProxyScore struct {
+	Rank       int                `json:"rank"`
+	Proxy      string             `json:"proxy"`
+	Score      float64            `json:"score"`
+	Components map[string]float64 `json:"components"`
+}
+
+// scoreInputs holds the raw per-proxy values the score is computed from
+type scoreInputs struct {
+	proxy       string
+	successRate float64
+	p50         float64
+	p95         float64
+	jitter      float64
+	throughput  float64
+	cost        float64
+	hasLatency  bool
+}
+
+// validateScoringWeights rejects negative weights and an all-zero model
+func validateScoringWeights(weights *ScoringWeights) error {
+	values := []float64{weights.SuccessRate, weights.P50, weights.P95, weights.Jitter, weights.Throughput, weights.Cost}
+	var total float64
+	for _, w := range values {
+		if w < 0 {
+			return errors.New("weights must not be negative")
+		}
+		total += w
+	}
+	if total == 0 {
+		return errors.New("at least one weight must be positive")
+	}
+	return nil
+}
+
+// rankProxies scores every proxy and returns them sorted best first. Success
+// rate is used as is; the other components are min-max normalized across the
+// proxies, with latency, jitter and cost treated as lower-is-better. Proxies
+// without a single successful request get zero for the latency components.
+// Because latency and throughput only describe the requests that succeeded,
+// those components are weighted by the success rate so a fast proxy that fails
+// most requests cannot outrank a reliable one.
+func (r *Reporter) rankProxies(metrics map[string]*Metrics) []*ProxyScore {
+	scoring := r.config.Report.Scoring
+	if scoring == nil || scoring.Weights == nil || len(metrics) == 0 {
+		return nil
+	}
+
+	inputs := make([]*scoreInputs, 0, len(metrics))
+	for _, m := range metrics {
+		inputs = append(inputs, collectScoreInputs(m, scoring.Costs))
+	}
+
+	p50 := newNormalizer(inputs, func(in *scoreInputs) (float64, bool) { return in.p50, in.hasLatency }, true)
+	p95 := newNormalizer(inputs, func(in *scoreInputs) (float64, bool) { return in.p95, in.hasLatency }, true)
+	jitter := newNormalizer(inputs, func(in *scoreInputs) (float64, bool) { return in.jitter, true }, true)
+	throughput := newNormalizer(inputs, func(in *scoreInputs) (float64, bool) { return in.throughput, true }, false)
+	cost := newNormalizer(inputs, func(in *scoreInputs) (float64, bool) { return in.cost, true }, true)
+
+	weights := scoring.Weights
+	totalWeight := weights.SuccessRate + weights.P50 + weights.P95 + weights.Jitter + weights.Throughput + weights.Cost
+
+	ranking := make([]*ProxyScore, 0, len(inputs))
+	for _, in := range inputs {
+		components := map[string]float64{
+			ScoreSuccessRate: in.successRate,
+			ScoreP50:         0,
+			ScoreP95:         0,
+			ScoreJitter:      jitter.score(in.jitter),
+			ScoreThroughput:  throughput.score(in.throughput),
+			ScoreCost:        cost.score(in.cost),
+		}
+		if in.hasLatency {
+			components[ScoreP50] = p50.score(in.p50)
+			components[ScoreP95] = p95.score(in.p95)
+		}
+
+		performance := weights.P50*components[ScoreP50] +
+			weights.P95*components[ScoreP95] +
+			weights.Jitter*components[ScoreJitter] +
+			weights.Throughput*components[ScoreThroughput] +
+			weights.Cost*components[ScoreCost]
+		weighted := weights.SuccessRate*in.successRate + in.successRate*performance
+
+		score := 0.0
+		if totalWeight > 0 {
+			score = 100 * weighted / totalWeight
+		}
+		ranking = append(ranking, &ProxyScore{
+			Proxy:      in.proxy,
+			Score:      score,
+			Components: components,
+		})
+	}
+
+	sort.SliceStable(ranking, func(i, j int) bool {
+		if ranking[i].Score != ranking[j].Score {
+			return ranking[i].Score > ranking[j].Score
+		}
+		return ranking[i].Proxy < ranking[j].Proxy
+	})
+	for i, score := range ranking {
+		score.Rank = i + 1
+	}
+	return ranking
+}
+
+// collectScoreInputs extracts the raw scoring values from a proxy's metrics
+func collectScoreInputs(m *Metrics, costs map[string]float64) *scoreInputs {
+	in := &scoreInputs{
+		proxy:  redactProxy(m.ProxyString),
+		jitter: m.PingMetrics.Jitter,
+		cost:   proxyCost(m.ProxyString, costs),
+	}
+
+	request := m.RequestMetrics
+	if request.Total > 0 {
+		in.successRate = float64(request.Successful) / float64(request.Total)
+	}
+
+	in.p50, in.hasLatency = requestPercentile(m, 50)
+	in.p95, _ = requestPercentile(m, 95)
+
+	// Throughput is response bytes per second spent in successful requests
+	if total := request.SuccessfulTime(); total > 0 {
+		in.throughput = float64(request.Bytes) / (total / 1e6)
+	}
+
+	return in
+}
+
+// proxyCost looks up a proxy's cost by ID, then by host:port
+func proxyCost(proxyString string, costs map[string]float64) float64 {
+	proxy, err := ParseProxy(proxyString)
+	if err != nil {
+		return costs[proxyString]
+	}
+	if cost, ok := costs[proxy.ID()]; ok {
+		return cost
+	}
+	return costs[proxy.Address()]
+}
+
+// normalizer maps raw values onto 0-1 using the range seen across proxies
+type normalizer struct {
+	min           float64
+	max           float64
+	lowerIsBetter bool
+}
+
+// newNormalizer finds the range of value over the inputs that have one
+func newNormalizer(inputs []*scoreInputs, value func(*scoreInputs) (float64, bool), lowerIsBetter bool) *normalizer {
+	n := &normalizer{min: math.Inf(1), max: math.Inf(-1), lowerIsBetter: lowerIsBetter}
+	for _, in := range inputs {
+		v, ok := value(in)
+		if !ok {
+			continue
+		}
+		n.min = math.Min(n.min, v)
+		n.max = math.Max(n.max, v)
+	}
+	return n
+}
+
+// score normalizes v so the best proxy gets 1 and the worst 0. If every proxy
+// has the same value they all get 1.
+func (n *normalizer) score(v float64) float64 {
+	if n.max <= n.min {
+		return 1
+	}
+	normalized := (v - n.min) / (n.max - n.min)
+	if n.lowerIsBetter {
+		return 1 - normalized
+	}
+	return normalized
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newScoringReporter(weights *ScoringWeights, costs map[string]float64) *Reporter {
+	config := &Config{
+		Statistics: StatisticsConfig{Mean: true, Median: true},
+		Report: ReportConfig{
+			Unit:    "ms",
+			Scoring: &ScoringConfig{Weights: weights, Costs: costs},
+		},
+	}
+	return NewReporter(config)
+}
+
+func scoringMetrics(proxyString string, successes, failures int, latency time.Duration) *Metrics {
+	m := NewMetrics(proxyString)
+	for i := 0; i < successes; i++ {
+		m.AddRequestTime(latency, true)
+		m.AddResponseBytes(1000)
+	}
+	for i := 0; i < failures; i++ {
+		m.AddRequestTime(0, false)
+	}
+	UpdateMetricsStatistics(m, &StatisticsConfig{Mean: true, Median: true})
+	return m
+}
+
+func TestRankingPenalizesFailures(t *testing.T) {
+	weights := &ScoringWeights{SuccessRate: 0.4, P50: 0.2, P95: 0.2, Jitter: 0.1, Throughput: 0.1}
+	reporter := newScoringReporter(weights, nil)
+
+	metrics := map[string]*Metrics{
+		"fast": scoringMetrics("http:fast:8080:::enabled", 1, 9, 10*time.Millisecond),
+		"slow": scoringMetrics("http:slow:8080:::enabled", 10, 0, 50*time.Millisecond),
+	}
+
+	ranking := reporter.rankProxies(metrics)
+	if len(ranking) != 2 {
+		t.Fatalf("expected 2 ranked proxies, got %d", len(ranking))
+	}
+	if ranking[0].Proxy != "http:slow:8080:::enabled" || ranking[0].Rank != 1 {
+		t.Errorf("expected reliable proxy to rank first, got %s", ranking[0].Proxy)
+	}
+	if ranking[1].Components[ScoreSuccessRate] != 0.1 {
+		t.Errorf("expected success rate component 0.1, got %v", ranking[1].Components[ScoreSuccessRate])
+	}
+	if ranking[1].Components[ScoreP50] != 1 || ranking[0].Components[ScoreP50] != 0 {
+		t.Errorf("expected fastest p50 to score 1 and slowest 0, got %v / %v",
+			ranking[1].Components[ScoreP50], ranking[0].Components[ScoreP50])
+	}
+}
+
+func TestRankingUsesCosts(t *testing.T) {
+	weights := &ScoringWeights{Cost: 1}
+	costs := map[string]float64{
+		"http://cheap:8080": 1,
+		"pricey:8080":       5,
+	}
+	reporter := newScoringReporter(weights, costs)
+
+	metrics := map[string]*Metrics{
+		"cheap":  scoringMetrics("http:cheap:8080:::enabled", 5, 0, 20*time.Millisecond),
+		"pricey": scoringMetrics("http:pricey:8080:::enabled", 5, 0, 20*time.Millisecond),
+	}
+
+	ranking := reporter.rankProxies(metrics)
+	if ranking[0].Proxy != "http:cheap:8080:::enabled" {
+		t.Errorf("expected cheaper proxy first, got %s", ranking[0].Proxy)
+	}
+	if ranking[0].Score != 100 || ranking[1].Score != 0 {
+		t.Errorf("expected scores 100 and 0, got %v and %v", ranking[0].Score, ranking[1].Score)
+	}
+}
+
+func TestRankingThroughputWithoutMean(t *testing.T) {
+	reporter := newScoringReporter(&ScoringWeights{Throughput: 1}, nil)
+
+	fast := NewMetrics("http:fast:8080:::enabled")
+	fast.EnableHistograms(3, false)
+	slow := NewMetrics("http:slow:8080:::enabled")
+	for i := 0; i < 5; i++ {
+		fast.AddRequestTime(10*time.Millisecond, true)
+		fast.AddResponseBytes(1000)
+		slow.AddRequestTime(100*time.Millisecond, true)
+		slow.AddResponseBytes(1000)
+	}
+	for _, m := range []*Metrics{fast, slow} {
+		UpdateMetricsStatistics(m, &StatisticsConfig{Median: true})
+	}
+
+	ranking := reporter.rankProxies(map[string]*Metrics{"fast": fast, "slow": slow})
+	if ranking[0].Proxy != "http:fast:8080:::enabled" || ranking[0].Components[ScoreThroughput] != 1 {
+		t.Errorf("expected the faster proxy to win on throughput without the mean, got %+v", ranking[0])
+	}
+	if ranking[1].Components[ScoreThroughput] != 0 {
+		t.Errorf("expected the slower proxy's throughput to score 0, got %v", ranking[1].Components[ScoreThroughput])
+	}
+}
+
+func TestValidateScoringWeights(t *testing.T) {
+	if err := validateScoringWeights(&ScoringWeights{SuccessRate: -1, P50: 2}); err == nil {
+		t.Error("expected error for negative weight")
+	}
+	if err := validateScoringWeights(&ScoringWeights{}); err == nil {
+		t.Error("expected error for all-zero weights")
+	}
+	if err := validateScoringWeights(&ScoringWeights{P95: 1}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
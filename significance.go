@@ -0,0 +1,139 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// ProxyComparison holds pairwise significance tests between proxies for one metric.
+// EffectSizes[i][j] is the rank-biserial correlation between proxy i and proxy j:
+// positive values mean proxy i tends to be slower. PValues[i][j] is the two-sided
+// Mann-Whitney U p-value.
+type ProxyComparison struct {
+	Metric                    string      `json:"metric"`
+	Proxies                   []string    `json:"proxies"`
+	Best                      string      `json:"best"`
+	Alpha                     float64     `json:"alpha"`
+	EffectSizes               [][]float64 `json:"effect_sizes"`
+	PValues                   [][]float64 `json:"p_values"`
+	IndistinguishableFromBest []string    `json:"indistinguishable_from_best"`
+}
+
+// MannWhitneyU runs a two-sided Mann-Whitney U test of a against b using the
+// normal approximation with tie and continuity correction. It returns the U
+// statistic for a, the rank-biserial effect size and the p-value.
+func MannWhitneyU(a, b []float64) (float64, float64, float64) {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 0, 0, 1
+	}
+
+	type rankedValue struct {
+		value float64
+		fromA bool
+	}
+	combined := make([]rankedValue, 0, len(a)+len(b))
+	for _, v := range a {
+		combined = append(combined, rankedValue{v, true})
+	}
+	for _, v := range b {
+		combined = append(combined, rankedValue{v, false})
+	}
+	sort.Slice(combined, func(i, j int) bool { return combined[i].value < combined[j].value })
+
+	// Assign average ranks to ties and accumulate the tie correction term
+	var rankSumA, tieTerm float64
+	for i := 0; i < len(combined); {
+		j := i
+		for j < len(combined) && combined[j].value == combined[i].value {
+			j++
+		}
+		averageRank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if combined[k].fromA {
+				rankSumA += averageRank
+			}
+		}
+		ties := float64(j - i)
+		tieTerm += ties*ties*ties - ties
+		i = j
+	}
+
+	u := rankSumA - n1*(n1+1)/2
+	effect := 2*u/(n1*n2) - 1
+
+	n := n1 + n2
+	variance := n1 * n2 / 12 * ((n + 1) - tieTerm/(n*(n-1)))
+	if variance <= 0 {
+		return u, effect, 1
+	}
+
+	diff := math.Abs(u-n1*n2/2) - 0.5
+	if diff < 0 {
+		diff = 0
+	}
+	z := diff / math.Sqrt(variance)
+	return u, effect, math.Erfc(z / math.Sqrt2)
+}
+
+// compareSamples builds the pairwise comparison matrix for the given samples
+// keyed by proxy. The best proxy is the one with the lowest median.
+func compareSamples(metric string, samples map[string][]float64, alpha float64) *ProxyComparison {
+	proxies := make([]string, 0, len(samples))
+	for proxy, values := range samples {
+		if len(values) > 0 {
+			proxies = append(proxies, proxy)
+		}
+	}
+	if len(proxies) < 2 {
+		return nil
+	}
+	sort.Strings(proxies)
+
+	comparison := &ProxyComparison{
+		Metric:                    metric,
+		Proxies:                   proxies,
+		Alpha:                     alpha,
+		EffectSizes:               make([][]float64, len(proxies)),
+		PValues:                   make([][]float64, len(proxies)),
+		IndistinguishableFromBest: make([]string, 0),
+	}
+
+	best, bestMedian := 0, math.Inf(1)
+	for i, proxy := range proxies {
+		if median := sampleMedian(samples[proxy]); median < bestMedian {
+			best, bestMedian = i, median
+		}
+
+		comparison.EffectSizes[i] = make([]float64, len(proxies))
+		comparison.PValues[i] = make([]float64, len(proxies))
+		for j, other := range proxies {
+			if i == j {
+				comparison.PValues[i][j] = 1
+				continue
+			}
+			_, effect, p := MannWhitneyU(samples[proxy], samples[other])
+			comparison.EffectSizes[i][j] = effect
+			comparison.PValues[i][j] = p
+		}
+	}
+
+	comparison.Best = proxies[best]
+	for i, proxy := range proxies {
+		if i != best && comparison.PValues[best][i] >= alpha {
+			comparison.IndistinguishableFromBest = append(comparison.IndistinguishableFromBest, proxy)
+		}
+	}
+	return comparison
+}
+
+// sampleMedian returns the median of values without modifying them
+func sampleMedian(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMannWhitneyUSeparatedSamples(t *testing.T) {
+	u, effect, p := MannWhitneyU([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10})
+	if u != 0 {
+		t.Errorf("expected U=0, got %v", u)
+	}
+	if effect != -1 {
+		t.Errorf("expected effect size -1, got %v", effect)
+	}
+	if math.Abs(p-0.0122) > 0.001 {
+		t.Errorf("expected p close to 0.0122, got %v", p)
+	}
+}
+
+func TestMannWhitneyUIdenticalSamples(t *testing.T) {
+	_, effect, p := MannWhitneyU([]float64{5, 5, 5, 5}, []float64{5, 5, 5, 5})
+	if effect != 0 || p != 1 {
+		t.Errorf("expected effect 0 and p 1 for identical samples, got %v / %v", effect, p)
+	}
+}
+
+func TestCompareSamplesFlagsIndistinguishable(t *testing.T) {
+	samples := map[string][]float64{
+		"fast":    {100, 102, 98, 101, 99, 103, 97, 100, 102, 98},
+		"similar": {101, 99, 103, 100, 98, 102, 100, 99, 101, 104},
+		"slow":    {200, 210, 190, 205, 195, 202, 198, 207, 193, 201},
+	}
+
+	comparison := compareSamples("request", samples, 0.05)
+	if comparison.Best != "fast" {
+		t.Errorf("expected fast to be best, got %s", comparison.Best)
+	}
+	if len(comparison.IndistinguishableFromBest) != 1 || comparison.IndistinguishableFromBest[0] != "similar" {
+		t.Errorf("expected only similar to be indistinguishable, got %v", comparison.IndistinguishableFromBest)
+	}
+	if len(comparison.PValues) != 3 || len(comparison.EffectSizes[0]) != 3 {
+		t.Errorf("expected a 3x3 matrix")
+	}
+}
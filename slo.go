@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SLO check names
+const (
+	SLOCheckLatency     = "latency"
+	SLOCheckSuccessRate = "success_rate"
+	SLOCheckErrorRate   = "error_rate"
+)
+
+// SLOResult is the evaluation of one SLO for one proxy
+type SLOResult struct {
+	Name   string      `json:"name"`
+	Passed bool        `json:"passed"`
+	Checks []*SLOCheck `json:"checks"`
+}
+
+// SLOCheck is a single objective within an SLO. Latencies are in the report's
+// display unit and rates in percent. Margin is the headroom to the target:
+// positive when the check passes, negative by how much it was missed.
+type SLOCheck struct {
+	Check  string  `json:"check"`
+	Class  string  `json:"class,omitempty"`
+	Target float64 `json:"target"`
+	Actual float64 `json:"actual"`
+	Margin float64 `json:"margin"`
+	Passed bool    `json:"passed"`
+}
+
+// ApdexResult is a proxy's Apdex score. Requests up to the satisfied threshold
+// count fully, up to the tolerating threshold half, and slower or failed
+// requests not at all.
+type ApdexResult struct {
+	Score               float64 `json:"score"`
+	SatisfiedThreshold  float64 `json:"satisfied_threshold"`
+	ToleratingThreshold float64 `json:"tolerating_threshold"`
+	Satisfied           int64   `json:"satisfied"`
+	Tolerating          int64   `json:"tolerating"`
+	Frustrated          int64   `json:"frustrated"`
+}
+
+// validateSLOs checks the SLO definitions for missing or out-of-range values
+func validateSLOs(slos []SLOConfig) error {
+	for i, slo := range slos {
+		name := slo.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if slo.LatencyMs > 0 && (slo.Percentile <= 0 || slo.Percentile > 100) {
+			return fmt.Errorf("SLO %s: percentile must be between 0 and 100", name)
+		}
+		if slo.MinSuccessRate < 0 || slo.MinSuccessRate > 100 {
+			return fmt.Errorf("SLO %s: min_success_rate must be a percentage", name)
+		}
+		if slo.LatencyMs <= 0 && slo.MinSuccessRate == 0 && len(slo.MaxErrorRates) == 0 {
+			return fmt.Errorf("SLO %s: no objectives defined", name)
+		}
+	}
+	return nil
+}
+
+// evaluateSLOs checks every configured SLO against a proxy's request metrics
+func (r *Reporter) evaluateSLOs(m *Metrics) []*SLOResult {
+	if len(r.config.Report.SLOs) == 0 {
+		return nil
+	}
+
+	request := m.RequestMetrics
+	successRate := 0.0
+	if request.Total > 0 {
+		successRate = float64(request.Successful) / float64(request.Total) * 100
+	}
+
+	results := make([]*SLOResult, 0, len(r.config.Report.SLOs))
+	for i, slo := range r.config.Report.SLOs {
+		result := &SLOResult{Name: slo.Name, Passed: true}
+		if result.Name == "" {
+			result.Name = fmt.Sprintf("slo_%d", i+1)
+		}
+
+		if slo.LatencyMs > 0 {
+			target := fromMicros(toMicros(slo.LatencyMs, "ms"), r.unit())
+			check := &SLOCheck{Check: SLOCheckLatency, Class: fmt.Sprintf("p%.1f", slo.Percentile), Target: target}
+			if latency, ok := requestPercentile(m, slo.Percentile); ok {
+				check.Actual = fromMicros(latency, r.unit())
+				check.Margin = target - check.Actual
+				check.Passed = check.Margin >= 0
+			} else {
+				// Without a single successful request the latency objective is missed
+				check.Margin = -target
+			}
+			result.Checks = append(result.Checks, check)
+		}
+
+		if slo.MinSuccessRate > 0 {
+			check := &SLOCheck{Check: SLOCheckSuccessRate, Target: slo.MinSuccessRate, Actual: successRate}
+			check.Margin = successRate - slo.MinSuccessRate
+			check.Passed = request.Total > 0 && check.Margin >= 0
+			result.Checks = append(result.Checks, check)
+		}
+
+		classes := make([]string, 0, len(slo.MaxErrorRates))
+		for class := range slo.MaxErrorRates {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			limit := slo.MaxErrorRates[class]
+			check := &SLOCheck{Check: SLOCheckErrorRate, Class: class, Target: limit}
+			if request.Total > 0 {
+				check.Actual = float64(requestFailures(request, class)) / float64(request.Total) * 100
+			}
+			check.Margin = limit - check.Actual
+			check.Passed = check.Margin >= 0
+			result.Checks = append(result.Checks, check)
+		}
+
+		for _, check := range result.Checks {
+			result.Passed = result.Passed && check.Passed
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// requestFailures returns the failed requests of an error class, or of all
+// classes for "any"
+func requestFailures(request RequestMetrics, class string) int {
+	if class == "any" {
+		return request.Failed
+	}
+	if failures, ok := request.FailuresByClass[class]; ok {
+		return failures.Count
+	}
+	return 0
+}
+
+// calculateApdex scores a proxy's requests against the Apdex thresholds
+func (r *Reporter) calculateApdex(m *Metrics) *ApdexResult {
+	apdex := r.config.Report.Apdex
+	if apdex == nil || m.RequestMetrics.Total == 0 {
+		return nil
+	}
+
+	satisfiedThreshold := int64(toMicros(apdex.SatisfiedMs, "ms"))
+	toleratingThreshold := int64(toMicros(apdex.ToleratingMs, "ms"))
+	result := &ApdexResult{
+		SatisfiedThreshold:  fromMicros(float64(satisfiedThreshold), r.unit()),
+		ToleratingThreshold: fromMicros(float64(toleratingThreshold), r.unit()),
+		Frustrated:          int64(m.RequestMetrics.Failed),
+	}
+
+	count := func(value, n int64) {
+		switch {
+		case value <= satisfiedThreshold:
+			result.Satisfied += n
+		case value <= toleratingThreshold:
+			result.Tolerating += n
+		default:
+			result.Frustrated += n
+		}
+	}
+	if histogram := m.RequestMetrics.Histogram; histogram != nil {
+		satisfied := histogram.CountAtOrBelow(satisfiedThreshold)
+		tolerating := histogram.CountAtOrBelow(toleratingThreshold)
+		result.Satisfied += satisfied
+		result.Tolerating += tolerating - satisfied
+		result.Frustrated += histogram.Count() - tolerating
+	} else {
+		for _, value := range m.GetRequestTimes() {
+			count(value, 1)
+		}
+	}
+
+	total := result.Satisfied + result.Tolerating + result.Frustrated
+	if total > 0 {
+		result.Score = (float64(result.Satisfied) + float64(result.Tolerating)/2) / float64(total)
+	}
+	return result
+}
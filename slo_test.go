@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func sloMetrics() *Metrics {
+	metrics := NewMetrics("http:proxy:8080:::enabled")
+	for i := 1; i <= 18; i++ {
+		metrics.AddRequestTime(time.Duration(i*50)*time.Millisecond, true)
+	}
+	metrics.AddRequestFailure(30*time.Second, context.DeadlineExceeded)
+	metrics.AddRequestFailure(time.Millisecond, errors.New("proxy returned 407"))
+	return metrics
+}
+
+func TestEvaluateSLOs(t *testing.T) {
+	reporter := NewReporter(&Config{Report: ReportConfig{
+		Unit: "ms",
+		SLOs: []SLOConfig{
+			{Name: "latency", Percentile: 95, LatencyMs: 800, MinSuccessRate: 99},
+			{Name: "errors", MaxErrorRates: map[string]float64{ErrorClassTimeout: 10, ErrorClassAuth: 1}},
+		},
+	}})
+
+	results := reporter.evaluateSLOs(sloMetrics())
+	if len(results) != 2 {
+		t.Fatalf("expected 2 SLO results, got %d", len(results))
+	}
+
+	latency := results[0]
+	if latency.Passed {
+		t.Error("expected latency SLO to fail")
+	}
+	// p95 of 50..900ms interpolates to 875ms; 90% success is 9 points short
+	if latency.Checks[0].Passed || latency.Checks[0].Margin != -75 {
+		t.Errorf("expected latency margin -75ms, got %+v", latency.Checks[0])
+	}
+	if success := latency.Checks[1]; success.Actual != 90 || math.Abs(success.Margin+9) > 1e-9 {
+		t.Errorf("expected success rate 90%% with margin -9, got %+v", success)
+	}
+
+	errorRates := results[1]
+	if len(errorRates.Checks) != 2 || errorRates.Checks[0].Class != ErrorClassAuth || errorRates.Checks[1].Class != ErrorClassTimeout {
+		t.Fatalf("expected auth and timeout checks in order, got %+v", errorRates.Checks)
+	}
+	if errorRates.Checks[0].Passed || !errorRates.Checks[1].Passed || errorRates.Passed {
+		t.Errorf("expected auth check to fail (5%% > 1%%) and timeout check to pass, got %+v", errorRates.Checks)
+	}
+}
+
+func TestCalculateApdex(t *testing.T) {
+	reporter := NewReporter(&Config{Report: ReportConfig{
+		Unit:  "ms",
+		Apdex: &ApdexConfig{SatisfiedMs: 200, ToleratingMs: 800},
+	}})
+
+	apdex := reporter.calculateApdex(sloMetrics())
+	// 50-200ms satisfied (4), 250-800ms tolerating (12), 850-900ms and failures frustrated (4)
+	if apdex.Satisfied != 4 || apdex.Tolerating != 12 || apdex.Frustrated != 4 {
+		t.Fatalf("expected 4/12/4, got %d/%d/%d", apdex.Satisfied, apdex.Tolerating, apdex.Frustrated)
+	}
+	if apdex.Score != 0.5 {
+		t.Errorf("expected Apdex 0.5, got %v", apdex.Score)
+	}
+}
+
+func TestCalculateApdexFromHistogram(t *testing.T) {
+	reporter := NewReporter(&Config{Report: ReportConfig{
+		Unit:  "ms",
+		Apdex: &ApdexConfig{SatisfiedMs: 100, ToleratingMs: 400},
+	}})
+	metrics := NewMetrics("http:proxy:8080:::enabled")
+	metrics.EnableHistograms(1, false)
+	// At one significant figure 100.5ms and 101ms share a bucket starting below 100ms
+	for _, ms := range []float64{50, 100.5, 101} {
+		metrics.AddRequestTime(time.Duration(ms*float64(time.Millisecond)), true)
+	}
+	metrics.AddRequestFailure(time.Second, context.DeadlineExceeded)
+
+	apdex := reporter.calculateApdex(metrics)
+	if apdex.Satisfied != 1 || apdex.Tolerating != 2 || apdex.Frustrated != 1 {
+		t.Fatalf("expected 1/2/1, got %d/%d/%d", apdex.Satisfied, apdex.Tolerating, apdex.Frustrated)
+	}
+}
@@ -54,6 +54,10 @@ func (s *SOCKS5Client) MakeRequest(ctx context.Context, targetURL string) ([]byt
 	}
 	defer resp.Body.Close()
 
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// SOCKS5 protocol constants (RFC 1928, RFC 1929)
+const (
+	socks5Version          = 0x05
+	socks5AuthNone         = 0x00
+	socks5AuthPassword     = 0x02
+	socks5AuthNoAcceptable = 0xff
+	socks5CmdUDPAssociate  = 0x03
+	socks5AddrIPv4         = 0x01
+	socks5AddrDomain       = 0x03
+	socks5AddrIPv6         = 0x04
+)
+
+// socks5Handshake performs the SOCKS5 greeting and, if requested by the server,
+// username/password authentication over conn
+func socks5Handshake(conn net.Conn, username, password string) error {
+	methods := []byte{socks5AuthNone}
+	if username != "" || password != "" {
+		methods = append(methods, socks5AuthPassword)
+	}
+
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return fmt.Errorf("failed to send SOCKS5 greeting: %w", err)
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("failed to read SOCKS5 greeting reply: %w", err)
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("unexpected SOCKS version %d", reply[0])
+	}
+
+	switch reply[1] {
+	case socks5AuthNone:
+		return nil
+	case socks5AuthPassword:
+		if len(username) > 255 || len(password) > 255 {
+			return errors.New("SOCKS5 username or password too long")
+		}
+		auth := []byte{0x01, byte(len(username))}
+		auth = append(auth, username...)
+		auth = append(auth, byte(len(password)))
+		auth = append(auth, password...)
+		if _, err := conn.Write(auth); err != nil {
+			return fmt.Errorf("failed to send SOCKS5 credentials: %w", err)
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return fmt.Errorf("failed to read SOCKS5 auth reply: %w", err)
+		}
+		if reply[1] != 0x00 {
+			return fmt.Errorf("SOCKS5 authentication failed (status %d)", reply[1])
+		}
+		return nil
+	case socks5AuthNoAcceptable:
+		return errors.New("SOCKS5 server accepted none of the offered auth methods")
+	default:
+		return fmt.Errorf("SOCKS5 server selected unsupported auth method %d", reply[1])
+	}
+}
+
+// socks5UDPAssociate sends a UDP ASSOCIATE request and returns the relay address
+func socks5UDPAssociate(conn net.Conn) (string, error) {
+	request := []byte{socks5Version, socks5CmdUDPAssociate, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0}
+	if _, err := conn.Write(request); err != nil {
+		return "", fmt.Errorf("failed to send UDP ASSOCIATE: %w", err)
+	}
+
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", fmt.Errorf("failed to read UDP ASSOCIATE reply: %w", err)
+	}
+	if header[1] != 0x00 {
+		return "", fmt.Errorf("UDP ASSOCIATE rejected (reply code %d)", header[1])
+	}
+
+	host, port, err := readSOCKS5Address(conn)
+	if err != nil {
+		return "", fmt.Errorf("failed to read UDP relay address: %w", err)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// readSOCKS5Address reads an ATYP-prefixed address and port
+func readSOCKS5Address(r io.Reader) (string, int, error) {
+	atyp := make([]byte, 1)
+	if _, err := io.ReadFull(r, atyp); err != nil {
+		return "", 0, err
+	}
+
+	var host string
+	switch atyp[0] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		size := net.IPv4len
+		if atyp[0] == socks5AddrIPv6 {
+			size = net.IPv6len
+		}
+		ip := make([]byte, size)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", 0, err
+		}
+		host = net.IP(ip).String()
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(r, length); err != nil {
+			return "", 0, err
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(r, domain); err != nil {
+			return "", 0, err
+		}
+		host = string(domain)
+	default:
+		return "", 0, fmt.Errorf("unknown address type %d", atyp[0])
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(r, port); err != nil {
+		return "", 0, err
+	}
+	return host, int(binary.BigEndian.Uint16(port)), nil
+}
+
+// encodeSOCKS5Address encodes host:port as ATYP, address and port
+func encodeSOCKS5Address(address string) ([]byte, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port in %s", address)
+	}
+
+	var buf []byte
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			buf = append([]byte{socks5AddrIPv4}, ip4...)
+		} else {
+			buf = append([]byte{socks5AddrIPv6}, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return nil, fmt.Errorf("host name too long: %s", host)
+		}
+		buf = append([]byte{socks5AddrDomain, byte(len(host))}, host...)
+	}
+	return binary.BigEndian.AppendUint16(buf, uint16(port)), nil
+}
+
+// UDPClient benchmarks UDP relaying through a SOCKS5 proxy
+type UDPClient struct {
+	proxy   *Proxy
+	timeout time.Duration
+}
+
+// UDPAssociation is an established SOCKS5 UDP relay session
+type UDPAssociation struct {
+	control net.Conn
+	relay   net.Conn
+	header  []byte
+	seq     uint64
+}
+
+// NewUDPClient creates a new UDP client for a SOCKS5 proxy
+func NewUDPClient(proxy *Proxy, timeout time.Duration) (*UDPClient, error) {
+	if proxy.Protocol != "socks" {
+		return nil, fmt.Errorf("UDP ASSOCIATE requires a SOCKS5 proxy, got %s", proxy.Protocol)
+	}
+	return &UDPClient{
+		proxy:   proxy,
+		timeout: timeout,
+	}, nil
+}
+
+// Associate negotiates a UDP association relaying datagrams to target (host:port)
+func (u *UDPClient) Associate(ctx context.Context, target string) (*UDPAssociation, error) {
+	header, err := encodeSOCKS5Address(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UDP target %s: %w", target, err)
+	}
+
+	dialer := &net.Dialer{
+		Timeout: u.timeout,
+	}
+	control, err := dialer.DialContext(ctx, "tcp", u.proxy.Address())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy %s: %w", u.proxy.Address(), err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		control.SetDeadline(deadline)
+	}
+
+	if err := socks5Handshake(control, u.proxy.Username, u.proxy.Password); err != nil {
+		control.Close()
+		return nil, err
+	}
+
+	relayAddr, err := socks5UDPAssociate(control)
+	if err != nil {
+		control.Close()
+		return nil, err
+	}
+	control.SetDeadline(time.Time{})
+
+	// Servers commonly answer with an unspecified address meaning "same host as the proxy"
+	relayHost, relayPort, _ := net.SplitHostPort(relayAddr)
+	if ip := net.ParseIP(relayHost); ip != nil && ip.IsUnspecified() {
+		relayAddr = net.JoinHostPort(u.proxy.Host, relayPort)
+	}
+
+	relay, err := dialer.DialContext(ctx, "udp", relayAddr)
+	if err != nil {
+		control.Close()
+		return nil, fmt.Errorf("failed to open UDP relay %s: %w", relayAddr, err)
+	}
+
+	return &UDPAssociation{
+		control: control,
+		relay:   relay,
+		header:  append([]byte{0x00, 0x00, 0x00}, header...),
+	}, nil
+}
+
+// RoundTrip sends one datagram and waits for its echo, returning the round-trip time
+func (a *UDPAssociation) RoundTrip(payload []byte, timeout time.Duration) (time.Duration, error) {
+	a.seq++
+	seq := make([]byte, 8)
+	binary.BigEndian.PutUint64(seq, a.seq)
+
+	datagram := append(append(append([]byte{}, a.header...), seq...), payload...)
+
+	start := time.Now()
+	a.relay.SetDeadline(start.Add(timeout))
+	if _, err := a.relay.Write(datagram); err != nil {
+		return 0, fmt.Errorf("failed to send datagram: %w", err)
+	}
+
+	buf := make([]byte, 65535)
+	for {
+		n, err := a.relay.Read(buf)
+		if err != nil {
+			return 0, fmt.Errorf("no echo for datagram %d: %w", a.seq, err)
+		}
+		data, err := stripSOCKS5UDPHeader(buf[:n])
+		if err != nil {
+			continue
+		}
+		// Ignore late echoes of earlier datagrams
+		if len(data) >= 8 && bytes.Equal(data[:8], seq) {
+			return time.Since(start), nil
+		}
+	}
+}
+
+// Close tears down the UDP association
+func (a *UDPAssociation) Close() error {
+	a.relay.Close()
+	return a.control.Close()
+}
+
+// stripSOCKS5UDPHeader removes the SOCKS5 UDP request header from a datagram
+func stripSOCKS5UDPHeader(datagram []byte) ([]byte, error) {
+	if len(datagram) < 4 || datagram[2] != 0x00 {
+		return nil, errors.New("invalid or fragmented SOCKS5 datagram")
+	}
+	reader := bytes.NewReader(datagram[3:])
+	if _, _, err := readSOCKS5Address(reader); err != nil {
+		return nil, err
+	}
+	return datagram[len(datagram)-reader.Len():], nil
+}
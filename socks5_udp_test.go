@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// startUDPEchoServer starts a UDP server that echoes every datagram
+func startUDPEchoServer(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start UDP echo server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			conn.WriteTo(buf[:n], addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// startSOCKS5Server starts a minimal SOCKS5 server supporting username/password
+// auth, CONNECT and UDP ASSOCIATE
+func startSOCKS5Server(t *testing.T, username, password string) *Proxy {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start SOCKS5 server: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSOCKS5(conn, username, password)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return &Proxy{Protocol: "socks", Host: host, Port: port, Username: username, Password: password, Status: "enabled"}
+}
+
+func serveSOCKS5(conn net.Conn, username, password string) {
+	defer conn.Close()
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+	conn.Write([]byte{socks5Version, socks5AuthPassword})
+
+	authHeader := make([]byte, 2)
+	if _, err := io.ReadFull(conn, authHeader); err != nil {
+		return
+	}
+	user := make([]byte, authHeader[1])
+	io.ReadFull(conn, user)
+	passLen := make([]byte, 1)
+	io.ReadFull(conn, passLen)
+	pass := make([]byte, passLen[0])
+	io.ReadFull(conn, pass)
+	if string(user) != username || string(pass) != password {
+		conn.Write([]byte{0x01, 0x01})
+		return
+	}
+	conn.Write([]byte{0x01, 0x00})
+
+	request := make([]byte, 3)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+	host, port, err := readSOCKS5Address(conn)
+	if err != nil {
+		return
+	}
+
+	switch request[1] {
+	case 0x01:
+		target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err != nil {
+			conn.Write([]byte{socks5Version, 0x05, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer target.Close()
+		conn.Write([]byte{socks5Version, 0x00, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+		go io.Copy(target, conn)
+		io.Copy(conn, target)
+	case socks5CmdUDPAssociate:
+		relay, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			return
+		}
+		defer relay.Close()
+		relayPort := relay.LocalAddr().(*net.UDPAddr).Port
+		reply := []byte{socks5Version, 0x00, 0x00, socks5AddrIPv4, 0, 0, 0, 0}
+		conn.Write(binary.BigEndian.AppendUint16(reply, uint16(relayPort)))
+		go relayUDP(relay)
+		// The association lives as long as the control connection
+		io.Copy(io.Discard, conn)
+	}
+}
+
+func relayUDP(relay net.PacketConn) {
+	var client net.Addr
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := relay.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if client == nil || from.String() == client.String() {
+			client = from
+			data, err := stripSOCKS5UDPHeader(buf[:n])
+			if err != nil {
+				continue
+			}
+			host, port, _ := readSOCKS5Address(bytes.NewReader(buf[3:n]))
+			target, err := net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+			if err != nil {
+				continue
+			}
+			relay.WriteTo(data, target)
+			continue
+		}
+		header, _ := encodeSOCKS5Address(from.String())
+		datagram := append(append([]byte{0, 0, 0}, header...), buf[:n]...)
+		relay.WriteTo(datagram, client)
+	}
+}
+
+func TestUDPAssociationRoundTrip(t *testing.T) {
+	echoAddr := startUDPEchoServer(t)
+	proxy := startSOCKS5Server(t, "user", "pass")
+
+	client, err := NewUDPClient(proxy, 2*time.Second)
+	if err != nil {
+		t.Fatalf("failed to create UDP client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	association, err := client.Associate(ctx, echoAddr)
+	if err != nil {
+		t.Fatalf("UDP ASSOCIATE failed: %v", err)
+	}
+	defer association.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := association.RoundTrip([]byte("ping"), time.Second); err != nil {
+			t.Fatalf("round trip %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestUDPAssociationBadCredentials(t *testing.T) {
+	proxy := startSOCKS5Server(t, "user", "pass")
+	proxy.Password = "wrong"
+
+	client, _ := NewUDPClient(proxy, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, err := client.Associate(ctx, "127.0.0.1:9"); err == nil {
+		t.Error("expected authentication failure")
+	}
+}
+
+func TestNewUDPClientRequiresSOCKS(t *testing.T) {
+	if _, err := NewUDPClient(&Proxy{Protocol: "http"}, time.Second); err == nil {
+		t.Error("expected error for non-SOCKS proxy")
+	}
+}
+
+func TestUDPMetricsLossRate(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.AddUDPAssociation(10*time.Millisecond, true)
+	metrics.AddUDPDatagram(5*time.Millisecond, true)
+	metrics.AddUDPDatagram(0, false)
+	metrics.AddUDPDatagram(6*time.Millisecond, true)
+	metrics.AddUDPDatagram(0, false)
+
+	if metrics.UDPMetrics.Sent != 4 || metrics.UDPMetrics.Received != 2 {
+		t.Errorf("expected 4 sent / 2 received, got %d / %d", metrics.UDPMetrics.Sent, metrics.UDPMetrics.Received)
+	}
+	if metrics.UDPMetrics.LossRate != 0.5 {
+		t.Errorf("expected loss rate 0.5, got %v", metrics.UDPMetrics.LossRate)
+	}
+}
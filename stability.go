@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// runStabilityTest holds long-lived tunnels through each proxy and records drops
+func (b *BenchmarkEngine) runStabilityTest() error {
+	var wg sync.WaitGroup
+
+	for _, proxy := range b.proxies {
+		wg.Add(1)
+		go func(p *Proxy) {
+			defer wg.Done()
+			b.runStabilityTestForProxy(p)
+		}(proxy)
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// runStabilityTestForProxy opens the configured heartbeat tunnels and idle probes
+// for a single proxy and waits until all of them finish or drop
+func (b *BenchmarkEngine) runStabilityTestForProxy(proxy *Proxy) {
+	stability := b.config.Benchmark.Stability
+	fmt.Printf("Running stability test for proxy %s (%d tunnels)...\n", proxy.Address(), stability.Tunnels)
+
+	timeout := time.Duration(b.config.Benchmark.TimeoutMs) * time.Millisecond
+	duration := time.Duration(stability.DurationMs) * time.Millisecond
+	interval := time.Duration(stability.HeartbeatIntervalMs) * time.Millisecond
+	heartbeat := []byte(stability.Heartbeat)
+	target := stability.Target
+	metrics := b.metrics[proxy.String()]
+	dialer := NewTunnelDialer(proxy, timeout)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < stability.Tunnels; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			conn, err := dialTunnel(dialer, target, timeout)
+			if err != nil {
+				fmt.Printf("Stability tunnel failed to open for proxy %s: %v\n", proxy.Address(), err)
+				metrics.AddStabilityTunnel(0, false, false)
+				return
+			}
+			defer conn.Close()
+
+			lifetime, err := holdTunnel(conn, heartbeat, interval, duration, timeout)
+			if err != nil {
+				fmt.Printf("Stability tunnel dropped for proxy %s after %v: %v\n", proxy.Address(), lifetime.Round(time.Second), err)
+			}
+			metrics.AddStabilityTunnel(lifetime, true, err != nil)
+		}()
+	}
+
+	for _, idleMs := range stability.IdleProbesMs {
+		wg.Add(1)
+		go func(idle time.Duration) {
+			defer wg.Done()
+
+			conn, err := dialTunnel(dialer, target, timeout)
+			if err != nil {
+				fmt.Printf("Idle probe tunnel failed to open for proxy %s: %v\n", proxy.Address(), err)
+				return
+			}
+			defer conn.Close()
+
+			err = probeIdleTunnel(conn, heartbeat, idle, timeout)
+			if err != nil {
+				fmt.Printf("Idle probe of %v killed tunnel for proxy %s: %v\n", idle, proxy.Address(), err)
+			}
+			metrics.AddIdleProbe(idle, err == nil)
+		}(time.Duration(idleMs) * time.Millisecond)
+	}
+
+	wg.Wait()
+}
+
+// dialTunnel opens a tunnel with its own establishment timeout
+func dialTunnel(dialer *TunnelDialer, target string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return dialer.DialContext(ctx, target)
+}
+
+// holdTunnel keeps conn open for duration, sending a heartbeat every interval and
+// waiting for its echo. It returns how long the tunnel stayed usable and an error
+// if it dropped before duration elapsed.
+func holdTunnel(conn net.Conn, heartbeat []byte, interval, duration, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+
+	for time.Since(start) < duration {
+		wait := interval
+		if remaining := duration - time.Since(start); remaining < wait {
+			wait = remaining
+		}
+		time.Sleep(wait)
+
+		if err := sendHeartbeat(conn, heartbeat, timeout); err != nil {
+			return time.Since(start), err
+		}
+	}
+
+	return time.Since(start), nil
+}
+
+// probeIdleTunnel leaves conn idle for idle and then checks it still carries data
+func probeIdleTunnel(conn net.Conn, heartbeat []byte, idle, timeout time.Duration) error {
+	time.Sleep(idle)
+	return sendHeartbeat(conn, heartbeat, timeout)
+}
+
+// sendHeartbeat writes the heartbeat payload and waits for any data to come back
+func sendHeartbeat(conn net.Conn, heartbeat []byte, timeout time.Duration) error {
+	conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if _, err := conn.Write(heartbeat); err != nil {
+		return fmt.Errorf("heartbeat write failed: %w", err)
+	}
+
+	buf := make([]byte, len(heartbeat))
+	if _, err := conn.Read(buf); err != nil {
+		return fmt.Errorf("heartbeat echo failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestHoldTunnelSurvives(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		io.Copy(server, server)
+	}()
+
+	lifetime, err := holdTunnel(client, []byte("\n"), 10*time.Millisecond, 50*time.Millisecond, time.Second)
+	if err != nil {
+		t.Fatalf("expected tunnel to survive, got %v", err)
+	}
+	if lifetime < 50*time.Millisecond {
+		t.Errorf("expected lifetime of at least 50ms, got %v", lifetime)
+	}
+}
+
+func TestHoldTunnelDetectsDrop(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		buf := make([]byte, 1)
+		server.Read(buf)
+		server.Write(buf)
+		server.Close()
+	}()
+
+	lifetime, err := holdTunnel(client, []byte("\n"), 10*time.Millisecond, time.Second, time.Second)
+	if err == nil {
+		t.Fatal("expected tunnel drop")
+	}
+	if lifetime >= time.Second {
+		t.Errorf("expected drop before the hold duration, got %v", lifetime)
+	}
+}
+
+func TestIdleTimeoutDetection(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.AddIdleProbe(30*time.Second, true)
+	metrics.AddIdleProbe(120*time.Second, false)
+	metrics.AddIdleProbe(60*time.Second, true)
+	metrics.AddIdleProbe(300*time.Second, false)
+
+	idle := metrics.StabilityMetrics.IdleTimeout
+	if idle == nil {
+		t.Fatal("expected idle timeout to be detected")
+	}
+	if idle.SurvivedMs != 60000 || idle.KilledMs != 120000 {
+		t.Errorf("expected idle timeout between 60000 and 120000 ms, got %d-%d", idle.SurvivedMs, idle.KilledMs)
+	}
+}
+
+func TestStabilityTunnelDrops(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.AddStabilityTunnel(0, false, false)
+	metrics.AddStabilityTunnel(10*time.Minute, true, false)
+	metrics.AddStabilityTunnel(90*time.Second, true, true)
+
+	stability := metrics.StabilityMetrics
+	if stability.Tunnels != 3 || stability.FailedTunnels != 1 || stability.Drops != 1 {
+		t.Errorf("unexpected counts: %+v", stability)
+	}
+	if len(stability.TimeToDrop) != 1 || stability.TimeToDrop[0] != 90000000 {
+		t.Errorf("expected one drop at 90000000us, got %v", stability.TimeToDrop)
+	}
+}
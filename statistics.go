@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"github.com/montanaflynn/stats"
+	"math/rand"
+	"sort"
 )
 
 // CalculateStatistics calculates statistical metrics for a set of values
@@ -22,8 +24,8 @@ func CalculateStatistics(values []int64, config *StatisticsConfig) *Statistics {
 	// Calculate min and max
 	min, _ := stats.Min(floatValues)
 	max, _ := stats.Max(floatValues)
-	stat.Min = int64(min)
-	stat.Max = int64(max)
+	stat.Min = min
+	stat.Max = max
 
 	// Calculate mean if requested
 	if config.Mean {
@@ -50,6 +52,139 @@ func CalculateStatistics(values []int64, config *StatisticsConfig) *Statistics {
 		}
 	}
 
+	// Calculate bootstrap confidence intervals if requested
+	if config.Bootstrap != nil && config.Bootstrap.Enabled && len(floatValues) > 1 {
+		stat.ConfidenceLevel = config.Bootstrap.Level
+		stat.ConfidenceIntervals = bootstrapConfidenceIntervals(floatValues, config)
+	}
+
+	// Detect outliers and calculate robust statistics if requested
+	if config.Outliers != nil && config.Outliers.Enabled {
+		stat.Robust = CalculateRobustStatistics(floatValues, config.Outliers)
+	}
+
+	return stat
+}
+
+// bootstrapConfidenceIntervals resamples values with replacement and returns
+// percentile-method confidence intervals for the mean, median and each
+// configured percentile. The resampling is seeded so runs are reproducible; the
+// seed is expected to have been set by the config defaults.
+func bootstrapConfidenceIntervals(values []float64, config *StatisticsConfig) map[string]*ConfidenceInterval {
+	bootstrap := config.Bootstrap
+	rng := rand.New(rand.NewSource(*bootstrap.Seed))
+
+	estimates := make(map[string][]float64)
+	resample := make([]float64, len(values))
+	for i := 0; i < bootstrap.Resamples; i++ {
+		for j := range resample {
+			resample[j] = values[rng.Intn(len(values))]
+		}
+		sort.Float64s(resample)
+
+		if config.Mean {
+			mean, _ := stats.Mean(resample)
+			estimates["mean"] = append(estimates["mean"], mean)
+		}
+		if config.Median {
+			median, _ := stats.Median(resample)
+			estimates["median"] = append(estimates["median"], median)
+		}
+		for _, p := range config.Percentiles {
+			value, _ := stats.Percentile(resample, p)
+			key := fmt.Sprintf("p%.1f", p)
+			estimates[key] = append(estimates[key], value)
+		}
+	}
+
+	alpha := (1 - bootstrap.Level) / 2 * 100
+	intervals := make(map[string]*ConfidenceInterval, len(estimates))
+	for key, distribution := range estimates {
+		lower, _ := stats.Percentile(distribution, alpha)
+		upper, _ := stats.Percentile(distribution, 100-alpha)
+		intervals[key] = &ConfidenceInterval{
+			Lower: lower,
+			Upper: upper,
+		}
+	}
+	return intervals
+}
+
+// CalculateHistogramStatistics calculates statistical metrics from a microsecond histogram
+func CalculateHistogramStatistics(histogram *Histogram, config *StatisticsConfig) *Statistics {
+	if histogram == nil || histogram.Count() == 0 {
+		return nil
+	}
+
+	stat := &Statistics{
+		Min:    float64(histogram.Min()),
+		Max:    float64(histogram.Max()),
+		StdDev: histogram.StdDev(),
+	}
+
+	if config.Mean {
+		stat.Mean = histogram.Mean()
+	}
+
+	if config.Median {
+		stat.Median = float64(histogram.ValueAtPercentile(50))
+	}
+
+	if len(config.Percentiles) > 0 {
+		stat.Percentiles = make(map[string]float64)
+		for _, p := range config.Percentiles {
+			stat.Percentiles[fmt.Sprintf("%.1f", p)] = float64(histogram.ValueAtPercentile(p))
+		}
+	}
+
+	return stat
+}
+
+// requestPercentile returns a percentile of the successful request times in
+// microseconds, from the histogram if enabled and the raw samples otherwise.
+// It reports false if there are no successful requests.
+func requestPercentile(m *Metrics, percentile float64) (float64, bool) {
+	if histogram := m.RequestMetrics.Histogram; histogram != nil {
+		if histogram.Count() == 0 {
+			return 0, false
+		}
+		return float64(histogram.ValueAtPercentile(percentile)), true
+	}
+
+	times := toFloat64s(m.GetRequestTimes())
+	if len(times) == 0 {
+		return 0, false
+	}
+	value, _ := stats.Percentile(times, percentile)
+	return value, true
+}
+
+// CalculateJitter returns the mean absolute difference between successive values
+func CalculateJitter(values []int64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+
+	var total float64
+	for i := 1; i < len(values); i++ {
+		diff := values[i] - values[i-1]
+		if diff < 0 {
+			diff = -diff
+		}
+		total += float64(diff)
+	}
+	return total / float64(len(values)-1)
+}
+
+// withSampleEstimates copies the confidence intervals and robust statistics of
+// sample-based statistics onto histogram statistics
+func withSampleEstimates(stat, samples *Statistics) *Statistics {
+	if stat == nil || samples == nil {
+		return stat
+	}
+	stat.ConfidenceLevel = samples.ConfidenceLevel
+	stat.ConfidenceIntervals = samples.ConfidenceIntervals
+	stat.Robust = samples.Robust
 	return stat
 }
 
@@ -57,5 +192,55 @@ func CalculateStatistics(values []int64, config *StatisticsConfig) *Statistics {
 func UpdateMetricsStatistics(metrics *Metrics, config *StatisticsConfig) {
 	metrics.RequestMetrics.Statistics = CalculateStatistics(metrics.GetRequestTimes(), config)
 	metrics.PingMetrics.Statistics = CalculateStatistics(metrics.GetPingTimes(), config)
+	metrics.PingMetrics.Jitter = CalculateJitter(metrics.GetPingTimes())
+	metrics.PingMetrics.HandshakeStatistics = CalculateStatistics(metrics.GetPingHandshakeTimes(), config)
 	metrics.DerivedMetrics.Statistics = CalculateStatistics(metrics.GetDerivedTimes(), config)
+
+	// Histograms, when enabled, replace the sample-based figures. Confidence
+	// intervals and robust statistics only exist if samples were kept.
+	if metrics.RequestMetrics.Histogram != nil {
+		metrics.RequestMetrics.Statistics = withSampleEstimates(CalculateHistogramStatistics(metrics.RequestMetrics.Histogram, config), metrics.RequestMetrics.Statistics)
+		metrics.PingMetrics.Statistics = withSampleEstimates(CalculateHistogramStatistics(metrics.PingMetrics.Histogram, config), metrics.PingMetrics.Statistics)
+		metrics.DerivedMetrics.Statistics = withSampleEstimates(CalculateHistogramStatistics(metrics.DerivedMetrics.Histogram, config), metrics.DerivedMetrics.Statistics)
+		metrics.PingMetrics.Jitter = metrics.GetPingJitter()
+	}
+
+	failed, failedByClass := metrics.GetFailedRequestTimes()
+	metrics.RequestMetrics.FailedStatistics = CalculateStatistics(failed, config)
+	for class, times := range failedByClass {
+		metrics.RequestMetrics.FailuresByClass[class].Statistics = CalculateStatistics(times, config)
+	}
+	if metrics.RequestMetrics.FailedHistogram != nil {
+		metrics.RequestMetrics.FailedStatistics = CalculateHistogramStatistics(metrics.RequestMetrics.FailedHistogram, config)
+		for _, failures := range metrics.RequestMetrics.FailuresByClass {
+			failures.Statistics = CalculateHistogramStatistics(failures.Histogram, config)
+		}
+	}
+
+	for _, bucket := range metrics.GetTimeSeries() {
+		bucket.Statistics = CalculateHistogramStatistics(bucket.histogram, config)
+	}
+
+	if metrics.TunnelMetrics != nil {
+		establish, echo := metrics.GetTunnelTimes()
+		metrics.TunnelMetrics.EstablishStatistics = CalculateStatistics(establish, config)
+		metrics.TunnelMetrics.EchoStatistics = CalculateStatistics(echo, config)
+	}
+
+	if metrics.UDPMetrics != nil {
+		setup, rtts := metrics.GetUDPTimes()
+		metrics.UDPMetrics.SetupStatistics = CalculateStatistics(setup, config)
+		metrics.UDPMetrics.RTTStatistics = CalculateStatistics(rtts, config)
+	}
+
+	if metrics.WebSocketMetrics != nil {
+		handshakes, rtts, timeToDrop := metrics.GetWebSocketTimes()
+		metrics.WebSocketMetrics.HandshakeStatistics = CalculateStatistics(handshakes, config)
+		metrics.WebSocketMetrics.RTTStatistics = CalculateStatistics(rtts, config)
+		metrics.WebSocketMetrics.TimeToDropStatistics = CalculateStatistics(timeToDrop, config)
+	}
+
+	if metrics.StabilityMetrics != nil {
+		metrics.StabilityMetrics.TimeToDropStatistics = CalculateStatistics(metrics.GetTimeToDrop(), config)
+	}
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// TCPClient handles raw TCP exchanges through proxy tunnels
+type TCPClient struct {
+	dialer  *TunnelDialer
+	timeout time.Duration
+	payload []byte
+	expect  *regexp.Regexp
+}
+
+// TCPResult holds the timings of a single tunnel exchange. Echo is only set if
+// Exchanged, i.e. a payload was sent or a response awaited.
+type TCPResult struct {
+	Establish time.Duration
+	Echo      time.Duration
+	Exchanged bool
+	Response  []byte
+}
+
+// NewTCPClient creates a new TCP client for a proxy using the given target settings
+func NewTCPClient(proxy *Proxy, timeout time.Duration, target *TCPTargetConfig) (*TCPClient, error) {
+	client := &TCPClient{
+		dialer:  NewTunnelDialer(proxy, timeout),
+		timeout: timeout,
+	}
+
+	if target != nil {
+		client.payload = []byte(target.Payload)
+		if target.Expect != "" {
+			expect, err := regexp.Compile(target.Expect)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tcp expect pattern %q: %w", target.Expect, err)
+			}
+			client.expect = expect
+		}
+	}
+
+	return client, nil
+}
+
+// Exchange opens a tunnel to address, optionally sends the payload and waits for
+// the expected response. Establish is the tunnel setup time, Echo the time from
+// sending the payload (or from tunnel setup if there is none) to the response.
+func (c *TCPClient) Exchange(ctx context.Context, address string) (*TCPResult, error) {
+	result := &TCPResult{}
+
+	start := time.Now()
+	conn, err := c.dialer.DialContext(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	result.Establish = time.Since(start)
+
+	// Nothing to send and nothing to wait for: the tunnel itself is the measurement
+	if len(c.payload) == 0 && c.expect == nil {
+		return result, nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	echoStart := time.Now()
+	if len(c.payload) > 0 {
+		if _, err := conn.Write(c.payload); err != nil {
+			return nil, fmt.Errorf("failed to send payload to %s: %w", address, err)
+		}
+	}
+
+	buf := make([]byte, 4096)
+	response := make([]byte, 0, len(buf))
+	for {
+		n, err := conn.Read(buf)
+		response = append(response, buf[:n]...)
+		if n > 0 && (c.expect == nil || c.expect.Match(response)) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("no expected response from %s: %w", address, err)
+		}
+	}
+	result.Echo = time.Since(echoStart)
+	result.Exchanged = true
+	result.Response = response
+
+	return result, nil
+}
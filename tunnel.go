@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// TunnelDialer opens raw TCP tunnels to arbitrary targets through a proxy
+type TunnelDialer struct {
+	proxy   *Proxy
+	timeout time.Duration
+}
+
+// NewTunnelDialer creates a new tunnel dialer for a proxy
+func NewTunnelDialer(p *Proxy, timeout time.Duration) *TunnelDialer {
+	return &TunnelDialer{
+		proxy:   p,
+		timeout: timeout,
+	}
+}
+
+// DialContext establishes a tunnel to address (host:port) through the proxy.
+// HTTP proxies are asked for a CONNECT tunnel, SOCKS proxies for a SOCKS5 CONNECT.
+func (t *TunnelDialer) DialContext(ctx context.Context, address string) (net.Conn, error) {
+	switch t.proxy.Protocol {
+	case "http", "https":
+		return t.dialHTTPConnect(ctx, address)
+	case "socks":
+		return t.dialSOCKS5(ctx, address)
+	default:
+		return nil, fmt.Errorf("unsupported protocol for tunnel: %s", t.proxy.Protocol)
+	}
+}
+
+// dialHTTPConnect opens a tunnel using the HTTP CONNECT method
+func (t *TunnelDialer) dialHTTPConnect(ctx context.Context, address string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout: t.timeout,
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", t.proxy.Address())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy %s: %w", t.proxy.Address(), err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	reader, err := t.writeConnect(conn, address)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	// Keep any bytes the target sent right after the CONNECT response
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// writeConnect sends a CONNECT request over conn and checks the proxy response
+func (t *TunnelDialer) writeConnect(conn net.Conn, address string) (*bufio.Reader, error) {
+	request := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", address, address)
+	if t.proxy.Username != "" || t.proxy.Password != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(t.proxy.Username + ":" + t.proxy.Password))
+		request += fmt.Sprintf("Proxy-Authorization: Basic %s\r\n", credentials)
+	}
+	request += "\r\n"
+
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return nil, fmt.Errorf("failed to send CONNECT to proxy %s: %w", t.proxy.Address(), err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CONNECT response from proxy %s: %w", t.proxy.Address(), err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy %s refused CONNECT to %s: %s", t.proxy.Address(), address, resp.Status)
+	}
+
+	return reader, nil
+}
+
+// dialSOCKS5 opens a tunnel using the SOCKS5 CONNECT command
+func (t *TunnelDialer) dialSOCKS5(ctx context.Context, address string) (net.Conn, error) {
+	auth := &proxy.Auth{
+		User:     t.proxy.Username,
+		Password: t.proxy.Password,
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", t.proxy.Address(), auth, &net.Dialer{Timeout: t.timeout})
+	if err != nil {
+		return nil, err
+	}
+
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return dialer.Dial("tcp", address)
+	}
+	return contextDialer.DialContext(ctx, "tcp", address)
+}
+
+// bufferedConn is a net.Conn whose reads are served from a bufio.Reader first
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Read reads from the buffered reader wrapping the connection
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
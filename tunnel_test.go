@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startEchoServer starts a TCP server that echoes everything it receives
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start echo server: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				io.Copy(c, c)
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// startConnectProxy starts a minimal HTTP CONNECT proxy that requires basic auth
+func startConnectProxy(t *testing.T, wantAuth string) *Proxy {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start proxy: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn) {
+				defer c.Close()
+				req, err := http.ReadRequest(bufio.NewReader(c))
+				if err != nil || req.Method != http.MethodConnect {
+					return
+				}
+				if req.Header.Get("Proxy-Authorization") != wantAuth {
+					io.WriteString(c, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+					return
+				}
+				target, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(c, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					return
+				}
+				defer target.Close()
+				io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go io.Copy(target, c)
+				io.Copy(c, target)
+			}(conn)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return &Proxy{Protocol: "http", Host: host, Port: port, Username: "user", Password: "pass", Status: "enabled"}
+}
+
+func TestTCPClientExchangeThroughConnectProxy(t *testing.T) {
+	echoAddr := startEchoServer(t)
+	proxy := startConnectProxy(t, "Basic dXNlcjpwYXNz")
+
+	client, err := NewTCPClient(proxy, 2*time.Second, &TCPTargetConfig{Payload: "PING\r\n", Expect: "PING"})
+	if err != nil {
+		t.Fatalf("failed to create TCP client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result, err := client.Exchange(ctx, echoAddr)
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	if !strings.Contains(string(result.Response), "PING") {
+		t.Errorf("expected echoed payload, got %q", result.Response)
+	}
+	if result.Establish <= 0 || !result.Exchanged {
+		t.Errorf("expected positive establish time and an exchange, got %+v", result)
+	}
+}
+
+func TestTunnelTimeWithoutExchange(t *testing.T) {
+	echoAddr := startEchoServer(t)
+	proxy := startConnectProxy(t, "Basic dXNlcjpwYXNz")
+
+	client, err := NewTCPClient(proxy, 2*time.Second, nil)
+	if err != nil {
+		t.Fatalf("failed to create TCP client: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	result, err := client.Exchange(ctx, echoAddr)
+	if err != nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+	if result.Exchanged {
+		t.Error("expected no exchange without payload or expect")
+	}
+
+	metrics := NewMetrics(proxy.String())
+	metrics.AddTunnelTime(result.Establish, result.Echo, result.Exchanged)
+	if len(metrics.TunnelMetrics.EstablishTimes) != 1 || len(metrics.TunnelMetrics.EchoTimes) != 0 {
+		t.Errorf("expected only an establish sample, got %+v", metrics.TunnelMetrics)
+	}
+}
+
+func TestTCPClientExchangeRejectedAuth(t *testing.T) {
+	echoAddr := startEchoServer(t)
+	proxy := startConnectProxy(t, "Basic other")
+
+	client, err := NewTCPClient(proxy, 2*time.Second, nil)
+	if err != nil {
+		t.Fatalf("failed to create TCP client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, err = client.Exchange(ctx, echoAddr)
+	if err == nil || !contains(err.Error(), "407") {
+		t.Errorf("expected 407 error, got %v", err)
+	}
+}
+
+func TestNewTCPClientInvalidPattern(t *testing.T) {
+	proxy := &Proxy{Protocol: "http", Host: "127.0.0.1", Port: "1"}
+	if _, err := NewTCPClient(proxy, time.Second, &TCPTargetConfig{Expect: "("}); err == nil {
+		t.Error("expected error for invalid expect pattern")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import "fmt"
+
+// displayUnits maps report display units to their size in microseconds
+var displayUnits = map[string]float64{
+	"ns": 0.001,
+	"us": 1,
+	"ms": 1000,
+	"s":  1000000,
+}
+
+// validateDisplayUnit checks that unit is a supported display unit
+func validateDisplayUnit(unit string) error {
+	if _, ok := displayUnits[unit]; !ok {
+		return fmt.Errorf("unsupported display unit %q (expected ns, us, ms or s)", unit)
+	}
+	return nil
+}
+
+// fromMicros converts a microsecond value to the given display unit
+func fromMicros(value float64, unit string) float64 {
+	size, ok := displayUnits[unit]
+	if !ok {
+		return value
+	}
+	return value / size
+}
+
+// toMicros converts a value in the given display unit to microseconds
+func toMicros(value float64, unit string) float64 {
+	size, ok := displayUnits[unit]
+	if !ok {
+		return value
+	}
+	return value * size
+}
+
+// scaleStatistics returns a copy of microsecond statistics converted to unit
+func scaleStatistics(stat *Statistics, unit string) *Statistics {
+	if stat == nil {
+		return nil
+	}
+
+	scaled := &Statistics{
+		Min:    fromMicros(stat.Min, unit),
+		Max:    fromMicros(stat.Max, unit),
+		Mean:   fromMicros(stat.Mean, unit),
+		Median: fromMicros(stat.Median, unit),
+		StdDev: fromMicros(stat.StdDev, unit),
+	}
+	if stat.Percentiles != nil {
+		scaled.Percentiles = make(map[string]float64, len(stat.Percentiles))
+		for key, value := range stat.Percentiles {
+			scaled.Percentiles[key] = fromMicros(value, unit)
+		}
+	}
+	if stat.ConfidenceIntervals != nil {
+		scaled.ConfidenceLevel = stat.ConfidenceLevel
+		scaled.ConfidenceIntervals = make(map[string]*ConfidenceInterval, len(stat.ConfidenceIntervals))
+		for key, interval := range stat.ConfidenceIntervals {
+			scaled.ConfidenceIntervals[key] = &ConfidenceInterval{
+				Lower: fromMicros(interval.Lower, unit),
+				Upper: fromMicros(interval.Upper, unit),
+			}
+		}
+	}
+	if stat.Robust != nil {
+		robust := *stat.Robust
+		robust.LowerFence = fromMicros(robust.LowerFence, unit)
+		robust.UpperFence = fromMicros(robust.UpperFence, unit)
+		robust.MeanWithoutOutliers = fromMicros(robust.MeanWithoutOutliers, unit)
+		robust.StdDevWithoutOutliers = fromMicros(robust.StdDevWithoutOutliers, unit)
+		robust.TrimmedMean = fromMicros(robust.TrimmedMean, unit)
+		robust.WinsorizedMean = fromMicros(robust.WinsorizedMean, unit)
+		robust.MAD = fromMicros(robust.MAD, unit)
+		robust.RobustStdDev = fromMicros(robust.RobustStdDev, unit)
+		robust.IQR = fromMicros(robust.IQR, unit)
+		robust.Outliers = make([]float64, len(stat.Robust.Outliers))
+		for i, v := range stat.Robust.Outliers {
+			robust.Outliers[i] = fromMicros(v, unit)
+		}
+		scaled.Robust = &robust
+	}
+	return scaled
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketClient handles WebSocket connections through proxy tunnels
+type WebSocketClient struct {
+	dialer  *TunnelDialer
+	timeout time.Duration
+	origin  string
+}
+
+// NewWebSocketClient creates a new WebSocket client for a proxy
+func NewWebSocketClient(proxy *Proxy, timeout time.Duration, origin string) *WebSocketClient {
+	return &WebSocketClient{
+		dialer:  NewTunnelDialer(proxy, timeout),
+		timeout: timeout,
+		origin:  origin,
+	}
+}
+
+// Connect opens a tunnel to the ws:// or wss:// target and performs the upgrade.
+// It returns the connection and the time taken by tunnel, TLS and upgrade handshake.
+func (w *WebSocketClient) Connect(ctx context.Context, targetURL string) (*websocket.Conn, time.Duration, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	origin := w.origin
+	if origin == "" {
+		origin = "http://" + target.Host
+	}
+	config, err := websocket.NewConfig(targetURL, origin)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start := time.Now()
+	conn, err := w.dialer.DialContext(ctx, websocketAddress(target))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if target.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: target.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, 0, fmt.Errorf("TLS handshake with %s failed: %w", target.Host, err)
+		}
+		conn = tlsConn
+	}
+
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, 0, fmt.Errorf("WebSocket upgrade failed: %w", err)
+	}
+	handshake := time.Since(start)
+
+	conn.SetDeadline(time.Time{})
+	return ws, handshake, nil
+}
+
+// RoundTrip sends payload as a text message and waits for the next message back
+func (w *WebSocketClient) RoundTrip(ws *websocket.Conn, payload string) (time.Duration, error) {
+	start := time.Now()
+	ws.SetDeadline(start.Add(w.timeout))
+
+	if err := websocket.Message.Send(ws, payload); err != nil {
+		return 0, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	var reply string
+	if err := websocket.Message.Receive(ws, &reply); err != nil {
+		return 0, fmt.Errorf("failed to receive message: %w", err)
+	}
+	return time.Since(start), nil
+}
+
+// websocketAddress returns host:port for a WebSocket URL, applying default ports
+func websocketAddress(target *url.URL) string {
+	if target.Port() != "" {
+		return target.Host
+	}
+	if target.Scheme == "wss" {
+		return net.JoinHostPort(target.Hostname(), "443")
+	}
+	return net.JoinHostPort(target.Hostname(), "80")
+}
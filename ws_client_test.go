@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocketClientThroughConnectProxy(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		io.Copy(ws, ws)
+	}))
+	defer server.Close()
+
+	proxy := startConnectProxy(t, "Basic dXNlcjpwYXNz")
+	client := NewWebSocketClient(proxy, 2*time.Second, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	targetURL := "ws://" + strings.TrimPrefix(server.URL, "http://")
+	ws, handshake, err := client.Connect(ctx, targetURL)
+	if err != nil {
+		t.Fatalf("WebSocket connect failed: %v", err)
+	}
+	defer ws.Close()
+
+	if handshake <= 0 {
+		t.Errorf("expected positive handshake time, got %v", handshake)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := client.RoundTrip(ws, "ping"); err != nil {
+			t.Fatalf("round trip %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestWebSocketClientDetectsDrop(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		ws.Close()
+	}))
+	defer server.Close()
+
+	proxy := startConnectProxy(t, "Basic dXNlcjpwYXNz")
+	client := NewWebSocketClient(proxy, time.Second, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ws, _, err := client.Connect(ctx, "ws://"+strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("WebSocket connect failed: %v", err)
+	}
+	defer ws.Close()
+
+	if _, err := client.RoundTrip(ws, "ping"); err == nil {
+		t.Error("expected round trip to fail on closed connection")
+	}
+}
+
+func TestWebSocketTimeToDrop(t *testing.T) {
+	metrics := NewMetrics("test-proxy")
+	metrics.AddWebSocketLifetime(10*time.Second, false)
+	metrics.AddWebSocketLifetime(10*time.Second, false)
+	metrics.AddWebSocketLifetime(3*time.Second, true)
+	UpdateMetricsStatistics(metrics, &StatisticsConfig{Mean: true})
+
+	ws := metrics.WebSocketMetrics
+	if ws.Drops != 1 || ws.Survived != 2 {
+		t.Errorf("expected 1 drop and 2 survivors, got %+v", ws)
+	}
+	if len(ws.TimeToDrop) != 1 || ws.TimeToDrop[0] != 3000000 {
+		t.Errorf("expected one drop at 3000000us, got %v", ws.TimeToDrop)
+	}
+	if ws.TimeToDropStatistics == nil || ws.TimeToDropStatistics.Mean != 3000000 {
+		t.Errorf("expected survivors to be left out of the time to drop, got %+v", ws.TimeToDropStatistics)
+	}
+}